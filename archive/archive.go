@@ -0,0 +1,105 @@
+// Package archive provides fs.FS views of zip, tar, and tar.gz archives, so
+// that doublestar's Glob and GlobWalk work inside of them just like they do on
+// a real file system:
+//
+//	matches, err := archive.GlobArchive("release.tar.gz", "**/*.so")
+//
+// Archives are indexed once, when they are opened. Every directory in an
+// archive can be read, including implicit ones that have no entry of their
+// own, which is common in zips and tars that were not created by archiving a
+// directory.
+package archive
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"strings"
+
+	"github.com/bmatcuk/doublestar/v4"
+)
+
+// ErrFormat is returned when an archive is not a zip, tar, or tar.gz.
+var ErrFormat = errors.New("archive: unknown archive format")
+
+// NestedSeparator separates the path of an archive from the path of an
+// archive inside of it in the names passed to Open and GlobArchive, such as
+// `outer.zip!/inner.tar`.
+const NestedSeparator = "!/"
+
+// NewFS detects the format of the archive in `r`, which is `size` bytes long,
+// and returns an FS for it. Zips are detected by their signature, gzipped
+// archives by the gzip header, and tars by the `ustar` magic that all modern
+// tar implementations write. Returns ErrFormat if the format is not
+// recognized. `r` must not be closed until the FS is no longer needed.
+func NewFS(r io.ReaderAt, size int64) (*FS, error) {
+	var header [512]byte
+	n, err := r.ReadAt(header[:], 0)
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+
+	switch {
+	case n >= 4 && string(header[:2]) == "PK" && (header[2] == 3 && header[3] == 4 || header[2] == 5 && header[3] == 6):
+		return NewZipFS(r, size)
+	case isGzip(header[:n]):
+		return NewTarFS(io.NewSectionReader(r, 0, size))
+	case n >= 262 && string(header[257:262]) == "ustar":
+		return NewTarFS(io.NewSectionReader(r, 0, size))
+	}
+	return nil, ErrFormat
+}
+
+// Open opens the archive `name`, detects its format with NewFS, and returns an
+// FS for it. The FS must be closed with Close once it is no longer needed.
+//
+// `name` may address an archive inside of another archive by separating their
+// paths with NestedSeparator: `outer.zip!/lib/inner.tar` opens the tar stored
+// as `lib/inner.tar` in `outer.zip`. Any number of archives may be nested. A
+// nested archive is read into memory.
+func Open(name string) (*FS, error) {
+	paths := strings.Split(name, NestedSeparator)
+	file, err := os.Open(paths[0])
+	if err != nil {
+		return nil, err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	fsys, err := NewFS(file, info.Size())
+	if err != nil {
+		file.Close()
+		return nil, &fs.PathError{Op: "open", Path: paths[0], Err: err}
+	}
+	fsys.closer = file
+
+	opened := paths[0]
+	for _, inner := range paths[1:] {
+		opened += NestedSeparator + inner
+		data, err := fs.ReadFile(fsys, inner)
+		fsys.Close()
+		if err != nil {
+			return nil, &fs.PathError{Op: "open", Path: opened, Err: err}
+		}
+		if fsys, err = NewFS(bytes.NewReader(data), int64(len(data))); err != nil {
+			return nil, &fs.PathError{Op: "open", Path: opened, Err: err}
+		}
+	}
+	return fsys, nil
+}
+
+// GlobArchive opens the archive `name` with Open, which may be nested, such
+// as `outer.zip!/inner.tar`, and returns the paths inside of it that match
+// `pattern`, just like doublestar.Glob. The options are passed to Glob.
+func GlobArchive(name, pattern string, opts ...doublestar.GlobOption) ([]string, error) {
+	fsys, err := Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer fsys.Close()
+	return doublestar.Glob(fsys, pattern, opts...)
+}
@@ -0,0 +1,297 @@
+package archive
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/bmatcuk/doublestar/v4"
+)
+
+// testEntry is an entry to write to a test archive. Names ending in a slash
+// are directories.
+type testEntry struct {
+	name string
+	data string
+	link string
+}
+
+// The files in every test archive. Some directories have no entries of their
+// own, and some names are not clean.
+var testEntries = []testEntry{
+	{name: "./bin/"},
+	{name: "./bin/tool", data: "#!/bin/sh"},
+	{name: "lib/x86_64/libfoo.so", data: "foo"},
+	{name: "lib/x86_64/libbar.so.1", data: "bar"},
+	{name: "/share/doc/README", data: "read me"},
+	{name: "share/empty/"},
+}
+
+// The same files as an fstest.MapFS, to compare against
+var testMapFS = fstest.MapFS{
+	"bin/tool":               {Data: []byte("#!/bin/sh")},
+	"lib/x86_64/libfoo.so":   {Data: []byte("foo")},
+	"lib/x86_64/libbar.so.1": {Data: []byte("bar")},
+	"share/doc/README":       {Data: []byte("read me")},
+	"share/empty":            {Mode: fs.ModeDir | 0755},
+}
+
+var testModTime = time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func makeTar(t *testing.T, entries []testEntry) []byte {
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	for _, e := range entries {
+		hdr := &tar.Header{Name: e.name, Mode: 0644, Size: int64(len(e.data)), ModTime: testModTime}
+		switch {
+		case e.link != "":
+			hdr.Typeflag, hdr.Linkname, hdr.Size = tar.TypeSymlink, e.link, 0
+		case e.name[len(e.name)-1] == '/':
+			hdr.Typeflag, hdr.Mode = tar.TypeDir, 0755
+		default:
+			hdr.Typeflag = tar.TypeReg
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			t.Fatal(err)
+		}
+		if hdr.Size > 0 {
+			tw.Write([]byte(e.data))
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func makeTarGz(t *testing.T, entries []testEntry) []byte {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	gz.Write(makeTar(t, entries))
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func makeZip(t *testing.T, entries []testEntry) []byte {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, e := range entries {
+		hdr := &zip.FileHeader{Name: e.name, Method: zip.Deflate, Modified: testModTime}
+		hdr.SetMode(0644)
+		if e.link != "" {
+			hdr.SetMode(fs.ModeSymlink | 0777)
+		}
+		w, err := zw.CreateHeader(hdr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if e.link != "" {
+			w.Write([]byte(e.link))
+		} else {
+			w.Write([]byte(e.data))
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func writeTemp(t *testing.T, name string, data []byte) string {
+	p := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(p, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+var testPatterns = []string{
+	"**", "**/*.so", "**/*.so*", "lib/*", "*/", "share/**", "bin/tool", "nope/**",
+	"{bin,share}/*", "**/README",
+}
+
+func TestFormats(t *testing.T) {
+	tarData := makeTar(t, testEntries)
+	formats := map[string][]byte{
+		"tar":    tarData,
+		"tar.gz": makeTarGz(t, testEntries),
+		"zip":    makeZip(t, testEntries),
+	}
+
+	for format, data := range formats {
+		fsys, err := NewFS(bytes.NewReader(data), int64(len(data)))
+		if err != nil {
+			t.Fatalf("NewFS(%v) error: %v", format, err)
+		}
+		if err := fstest.TestFS(fsys, "bin/tool", "lib/x86_64/libfoo.so", "share/doc/README", "share/empty"); err != nil {
+			t.Errorf("NewFS(%v) is not a valid fs.FS: %v", format, err)
+		}
+		testGlobs(t, format, fsys)
+	}
+
+	// a tar file is read on demand, rather than into memory
+	p := writeTemp(t, "release.tar", tarData)
+	fsys, err := Open(p)
+	if err != nil {
+		t.Fatalf("Open(`release.tar`) error: %v", err)
+	}
+	defer fsys.Close()
+	if err := fstest.TestFS(fsys, "bin/tool", "lib/x86_64/libfoo.so", "share/doc/README"); err != nil {
+		t.Errorf("Open(`release.tar`) is not a valid fs.FS: %v", err)
+	}
+	testGlobs(t, "release.tar", fsys)
+}
+
+// Globbing the FS must return the same matches as globbing the MapFS
+func testGlobs(t *testing.T, format string, fsys fs.FS) {
+	for _, pattern := range testPatterns {
+		expected, _ := doublestar.Glob(testMapFS, pattern)
+		matches, err := doublestar.Glob(fsys, pattern)
+		if err != nil || !compareSlices(matches, expected) {
+			t.Errorf("Glob(%v, %#q) = %v, %v want %v", format, pattern, matches, err, expected)
+		}
+	}
+}
+
+func TestSymlinks(t *testing.T) {
+	entries := append([]testEntry{
+		{name: "lib/libfoo.so", link: "x86_64/libfoo.so"},
+		{name: "lib64", link: "/lib/x86_64"},
+		{name: "broken", link: "nope"},
+		{name: "escape", link: "../../etc/passwd"},
+		{name: "loop", link: "loop"},
+	}, testEntries...)
+
+	for format, data := range map[string][]byte{"tar": makeTar(t, entries), "zip": makeZip(t, entries)} {
+		fsys, err := NewFS(bytes.NewReader(data), int64(len(data)))
+		if err != nil {
+			t.Fatalf("NewFS(%v) error: %v", format, err)
+		}
+
+		if b, err := fs.ReadFile(fsys, "lib/libfoo.so"); err != nil || string(b) != "foo" {
+			t.Errorf("ReadFile(%v, `lib/libfoo.so`) = %q, %v want `foo`", format, b, err)
+		}
+		if info, err := fs.Stat(fsys, "lib64"); err != nil || !info.IsDir() || info.Name() != "lib64" {
+			t.Errorf("Stat(%v, `lib64`) = %v, %v want a directory named lib64", format, info, err)
+		}
+		for _, name := range []string{"broken", "escape", "loop"} {
+			if _, err := fs.Stat(fsys, name); err == nil {
+				t.Errorf("Stat(%v, %#q) has no error", format, name)
+			}
+		}
+
+		// symlinks are followed, just like on a real file system
+		matches, err := doublestar.Glob(fsys, "lib*/**/*.so")
+		expected := []string{"lib/libfoo.so", "lib/x86_64/libfoo.so", "lib64/libfoo.so"}
+		if err != nil || !compareSlices(matches, expected) {
+			t.Errorf("Glob(%v, `lib*/**/*.so`) = %v, %v want %v", format, matches, err, expected)
+		}
+	}
+}
+
+func TestTarHardLinks(t *testing.T) {
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	tw.WriteHeader(&tar.Header{Name: "a", Typeflag: tar.TypeReg, Size: 3, Mode: 0644})
+	tw.Write([]byte("abc"))
+	tw.WriteHeader(&tar.Header{Name: "b", Typeflag: tar.TypeLink, Linkname: "a", Mode: 0644})
+	tw.WriteHeader(&tar.Header{Name: "c", Typeflag: tar.TypeLink, Linkname: "nope", Mode: 0644})
+	// a chain of links to links, and a loop
+	for i := 0; i < 8; i++ {
+		target := "a"
+		if i > 0 {
+			target = fmt.Sprintf("l%v", i-1)
+		}
+		tw.WriteHeader(&tar.Header{Name: fmt.Sprintf("l%v", i), Typeflag: tar.TypeLink, Linkname: target, Mode: 0644})
+	}
+	tw.WriteHeader(&tar.Header{Name: "x", Typeflag: tar.TypeLink, Linkname: "y", Mode: 0644})
+	tw.WriteHeader(&tar.Header{Name: "y", Typeflag: tar.TypeLink, Linkname: "x", Mode: 0644})
+	tw.Close()
+
+	fsys, err := NewTarFS(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatalf("NewTarFS() error: %v", err)
+	}
+	if b, err := fs.ReadFile(fsys, "b"); err != nil || string(b) != "abc" {
+		t.Errorf("ReadFile(`b`) = %q, %v want `abc`", b, err)
+	}
+	if b, err := fs.ReadFile(fsys, "c"); err != nil || len(b) != 0 {
+		t.Errorf("ReadFile(`c`) = %q, %v want nothing", b, err)
+	}
+	if b, err := fs.ReadFile(fsys, "l7"); err != nil || string(b) != "abc" {
+		t.Errorf("ReadFile(`l7`) = %q, %v want `abc`", b, err)
+	}
+	if b, err := fs.ReadFile(fsys, "x"); err != nil || len(b) != 0 {
+		t.Errorf("ReadFile(`x`) = %q, %v want nothing", b, err)
+	}
+}
+
+func TestGlobArchive(t *testing.T) {
+	inner := makeTarGz(t, testEntries)
+	outer := makeZip(t, []testEntry{
+		{name: "pkg/inner.tar.gz", data: string(inner)},
+		{name: "pkg/outer.so", data: "outer"},
+	})
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "outer.zip"), outer, 0644); err != nil {
+		t.Fatal(err)
+	}
+	outerPath := filepath.Join(dir, "outer.zip")
+
+	tests := []struct {
+		name     string
+		pattern  string
+		expected []string
+	}{
+		{outerPath, "**/*.so", []string{"pkg/outer.so"}},
+		{outerPath + "!/pkg/inner.tar.gz", "**/*.so", []string{"lib/x86_64/libfoo.so"}},
+		{outerPath + "!/pkg/inner.tar.gz", "lib/**", []string{"lib", "lib/x86_64", "lib/x86_64/libbar.so.1", "lib/x86_64/libfoo.so"}},
+	}
+	for idx, tt := range tests {
+		matches, err := GlobArchive(tt.name, tt.pattern)
+		if err != nil || !compareSlices(matches, tt.expected) {
+			t.Errorf("#%v. GlobArchive(%#q, %#q) = %v, %v want %v", idx, tt.name, tt.pattern, matches, err, tt.expected)
+		}
+	}
+
+	if _, err := GlobArchive(outerPath+"!/pkg/outer.so", "**"); !errors.Is(err, ErrFormat) {
+		t.Errorf("GlobArchive(`outer.zip!/pkg/outer.so`) error = %v want ErrFormat", err)
+	}
+	if _, err := GlobArchive(outerPath+"!/nope.tar", "**"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("GlobArchive(`outer.zip!/nope.tar`) error = %v want fs.ErrNotExist", err)
+	}
+	if _, err := GlobArchive(outerPath, "a/["); err != doublestar.ErrBadPattern {
+		t.Errorf("GlobArchive(`outer.zip`, `a/[`) error = %v want ErrBadPattern", err)
+	}
+	if _, err := NewFS(bytes.NewReader([]byte("not an archive")), 14); err != ErrFormat {
+		t.Errorf("NewFS(`not an archive`) error = %v want ErrFormat", err)
+	}
+}
+
+func compareSlices(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	a = append([]string(nil), a...)
+	b = append([]string(nil), b...)
+	sort.Strings(a)
+	sort.Strings(b)
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,339 @@
+package archive
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+	"time"
+)
+
+// FS is a read-only fs.FS view of an archive. The archive is indexed once,
+// when the FS is created: every directory in the archive, including implicit
+// ones that only exist because entries are stored beneath them, can be read
+// with ReadDir, and Stat and Open follow symlinks that point inside the
+// archive. FS implements fs.ReadDirFS and fs.StatFS, so doublestar's Glob and
+// GlobWalk never need to open anything.
+//
+// An FS is safe for concurrent use by multiple goroutines, as long as the
+// underlying reader is.
+type FS struct {
+	root    *entry
+	entries map[string]*entry
+	closer  io.Closer
+}
+
+// entry is a file, directory, or symlink in an archive. It implements both
+// fs.FileInfo and fs.DirEntry.
+type entry struct {
+	name    string
+	mode    fs.FileMode
+	size    int64
+	modTime time.Time
+
+	// sorted by name
+	children []*entry
+
+	// opens the contents of a file, or of a symlink, which is its target
+	open func() (io.ReadCloser, error)
+
+	// for hard links in tars, the path of the entry they link to
+	hardLink string
+}
+
+func (e *entry) Name() string               { return e.name }
+func (e *entry) Size() int64                { return e.size }
+func (e *entry) Mode() fs.FileMode          { return e.mode }
+func (e *entry) ModTime() time.Time         { return e.modTime }
+func (e *entry) IsDir() bool                { return e.mode.IsDir() }
+func (e *entry) Sys() interface{}           { return nil }
+func (e *entry) Type() fs.FileMode          { return e.mode.Type() }
+func (e *entry) Info() (fs.FileInfo, error) { return e, nil }
+
+// maxSymlinks is how many symlinks will be followed while resolving a path
+// before giving up, just like Linux's MAXSYMLINKS
+const maxSymlinks = 40
+
+var errTooManySymlinks = errors.New("too many levels of symbolic links")
+
+func newFS() *FS {
+	root := &entry{name: ".", mode: fs.ModeDir | 0555}
+	return &FS{root: root, entries: map[string]*entry{".": root}}
+}
+
+// Adds an entry named `name` in the archive, creating its parent directories
+// if they don't exist. Names are cleaned as if they were absolute, so a
+// leading `/` or `./` is removed and `../a` is treated as `a`. If an entry
+// has the same name as an earlier one, it replaces it, unless one of them is a
+// directory, in which case the directory is kept.
+func (f *FS) add(name string, e *entry) {
+	name = path.Clean("/" + name)[1:]
+	if name == "" || !fs.ValidPath(name) {
+		return
+	}
+
+	if prev, ok := f.entries[name]; ok {
+		if prev.IsDir() {
+			if e.IsDir() {
+				prev.mode, prev.modTime = e.mode, e.modTime
+			}
+			return
+		}
+		if !e.IsDir() {
+			*prev = *e
+			prev.name = path.Base(name)
+			return
+		}
+		// a directory replaces a file
+		e.children = prev.children
+		*prev = *e
+		prev.name = path.Base(name)
+		return
+	}
+
+	parent := f.dir(path.Dir(name))
+	e.name = path.Base(name)
+	parent.children = append(parent.children, e)
+	f.entries[name] = e
+}
+
+// Returns the directory `name`, creating it and its parents if necessary. If
+// a file is in the way, it becomes a directory.
+func (f *FS) dir(name string) *entry {
+	if e, ok := f.entries[name]; ok {
+		if !e.IsDir() {
+			e.mode = fs.ModeDir | 0555
+			e.size = 0
+			e.open = nil
+			e.hardLink = ""
+		}
+		return e
+	}
+	e := &entry{name: path.Base(name), mode: fs.ModeDir | 0555}
+	parent := f.dir(path.Dir(name))
+	parent.children = append(parent.children, e)
+	f.entries[name] = e
+	return e
+}
+
+// Sorts every directory's children and resolves hard links. Must be called
+// once every entry has been added.
+func (f *FS) finish() {
+	for _, e := range f.entries {
+		if e.IsDir() {
+			sort.Slice(e.children, func(i, j int) bool { return e.children[i].name < e.children[j].name })
+		}
+	}
+	for _, e := range f.entries {
+		if e.hardLink != "" {
+			f.resolveHardLink(e)
+		}
+	}
+}
+
+// Gives the hard link `e` the contents of its target, following links to
+// other hard links, which may not have been resolved yet.
+func (f *FS) resolveHardLink(e *entry) {
+	t, seen := e, make(map[*entry]bool)
+	for t != nil && t.hardLink != "" && !seen[t] {
+		seen[t] = true
+		next, ok := f.entries[path.Clean("/" + t.hardLink)[1:]]
+		if !ok || !next.mode.IsRegular() {
+			next = nil
+		}
+		t = next
+	}
+	if t != nil && t.hardLink == "" {
+		e.size, e.open = t.size, t.open
+	} else {
+		// the target is missing or the links form a loop, so there's nothing
+		// to read
+		e.size, e.open = 0, nil
+	}
+	e.hardLink = ""
+}
+
+// Resolves `name` to an entry, following symlinks in any of its parent
+// directories and, if `followLast` is true, in the last segment, too.
+func (f *FS) resolve(op, name string, followLast bool) (*entry, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: op, Path: name, Err: fs.ErrInvalid}
+	}
+	e, err := f.walk(name, followLast, maxSymlinks)
+	if err != nil {
+		return nil, &fs.PathError{Op: op, Path: name, Err: err}
+	}
+	if base := path.Base(name); e.name != base {
+		// a symlink was followed, but, like os.Stat, the result has the name
+		// of the symlink
+		renamed := *e
+		renamed.name = base
+		e = &renamed
+	}
+	return e, nil
+}
+
+func (f *FS) walk(name string, followLast bool, hops int) (*entry, error) {
+	if name == "." {
+		return f.root, nil
+	}
+
+	cur, real := f.root, ""
+	segments := strings.Split(name, "/")
+	for i, segment := range segments {
+		if !cur.IsDir() {
+			return nil, fs.ErrNotExist
+		}
+		j := sort.Search(len(cur.children), func(j int) bool { return cur.children[j].name >= segment })
+		if j == len(cur.children) || cur.children[j].name != segment {
+			return nil, fs.ErrNotExist
+		}
+		next := cur.children[j]
+		real = path.Join(real, segment)
+
+		if next.mode&fs.ModeSymlink != 0 && (followLast || i < len(segments)-1) {
+			if hops == 0 {
+				return nil, errTooManySymlinks
+			}
+			hops--
+			target, err := readLink(next)
+			if err != nil {
+				return nil, err
+			}
+			if strings.HasPrefix(target, "/") {
+				// absolute links are relative to the root of the archive
+				target = path.Clean(target)[1:]
+			} else {
+				target = path.Join(path.Dir(real), target)
+			}
+			if target == "" {
+				target = "."
+			}
+			if !fs.ValidPath(target) {
+				// points outside of the archive
+				return nil, fs.ErrNotExist
+			}
+			var err2 error
+			if next, err2 = f.walk(target, true, hops); err2 != nil {
+				return nil, err2
+			}
+			real = target
+		}
+		cur = next
+	}
+	return cur, nil
+}
+
+func readLink(e *entry) (string, error) {
+	if e.open == nil {
+		return "", fs.ErrNotExist
+	}
+	r, err := e.open()
+	if err != nil {
+		return "", err
+	}
+	defer r.Close()
+	b, err := io.ReadAll(r)
+	return string(b), err
+}
+
+// Open opens the named file or directory, following symlinks.
+func (f *FS) Open(name string) (fs.File, error) {
+	e, err := f.resolve("open", name, true)
+	if err != nil {
+		return nil, err
+	}
+	if e.IsDir() {
+		return &dirFile{entry: e}, nil
+	}
+
+	var r io.ReadCloser = io.NopCloser(strings.NewReader(""))
+	if e.open != nil {
+		if r, err = e.open(); err != nil {
+			return nil, &fs.PathError{Op: "open", Path: name, Err: err}
+		}
+	}
+	return &file{entry: e, ReadCloser: r}, nil
+}
+
+// ReadDir implements fs.ReadDirFS.
+func (f *FS) ReadDir(name string) ([]fs.DirEntry, error) {
+	e, err := f.resolve("readdir", name, true)
+	if err != nil {
+		return nil, err
+	}
+	if !e.IsDir() {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: errNotDir}
+	}
+	entries := make([]fs.DirEntry, len(e.children))
+	for i, c := range e.children {
+		entries[i] = c
+	}
+	return entries, nil
+}
+
+var errNotDir = errors.New("not a directory")
+
+// Stat implements fs.StatFS. Like os.Stat, it follows symlinks.
+func (f *FS) Stat(name string) (fs.FileInfo, error) {
+	e, err := f.resolve("stat", name, true)
+	if err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+// Close closes the archive file if it was opened by Open. Otherwise, it does
+// nothing.
+func (f *FS) Close() error {
+	if f.closer == nil {
+		return nil
+	}
+	return f.closer.Close()
+}
+
+type file struct {
+	*entry
+	io.ReadCloser
+}
+
+func (f *file) Stat() (fs.FileInfo, error) {
+	return f.entry, nil
+}
+
+type dirFile struct {
+	*entry
+	offset int
+}
+
+func (d *dirFile) Stat() (fs.FileInfo, error) {
+	return d.entry, nil
+}
+
+func (d *dirFile) Read([]byte) (int, error) {
+	return 0, &fs.PathError{Op: "read", Path: d.name, Err: errIsDir}
+}
+
+var errIsDir = errors.New("is a directory")
+
+func (d *dirFile) Close() error {
+	return nil
+}
+
+func (d *dirFile) ReadDir(n int) ([]fs.DirEntry, error) {
+	remaining := d.children[d.offset:]
+	if n > 0 && len(remaining) == 0 {
+		return nil, io.EOF
+	}
+	if n > 0 && n < len(remaining) {
+		remaining = remaining[:n]
+	}
+	entries := make([]fs.DirEntry, len(remaining))
+	for i, c := range remaining {
+		entries[i] = c
+	}
+	d.offset += len(remaining)
+	return entries, nil
+}
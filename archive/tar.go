@@ -0,0 +1,135 @@
+package archive
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"io/fs"
+	"strings"
+)
+
+// readSeekerAt is implemented by readers, such as *os.File, whose contents
+// can be read again later without buffering them
+type readSeekerAt interface {
+	io.Reader
+	io.ReaderAt
+	io.Seeker
+}
+
+// NewTarFS reads a tar archive from `r` and returns an FS for it. If the
+// archive is compressed with gzip, as in a .tar.gz or .tgz file, it is
+// decompressed. `r` is read to the end.
+//
+// If `r` also implements io.ReaderAt and io.Seeker, as *os.File does, and the
+// archive is not compressed, only the headers are read while indexing: the
+// contents of files are read from `r` when they are opened, so `r` must not be
+// closed until the FS is no longer needed. Otherwise, the contents of every
+// file are kept in memory.
+func NewTarFS(r io.Reader) (*FS, error) {
+	if rs, ok := r.(readSeekerAt); ok {
+		start, err := rs.Seek(0, io.SeekCurrent)
+		if err != nil {
+			return nil, err
+		}
+		var magic [2]byte
+		if n, _ := rs.ReadAt(magic[:], start); n < len(magic) || !isGzip(magic[:]) {
+			return indexTar(rs, rs)
+		}
+	}
+
+	br := bufio.NewReader(r)
+	if magic, _ := br.Peek(2); isGzip(magic) {
+		gz, err := gzip.NewReader(br)
+		if err != nil {
+			return nil, err
+		}
+		defer gz.Close()
+		return indexTar(gz, nil)
+	}
+	return indexTar(br, nil)
+}
+
+// Indexes the tar in `r`. If `rs` is not nil, it must be the same reader as
+// `r`, and the contents of regular files are read from it later. Otherwise,
+// they are read into memory.
+func indexTar(r io.Reader, rs readSeekerAt) (*FS, error) {
+	f := newFS()
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		e := &entry{
+			mode:    hdr.FileInfo().Mode(),
+			size:    hdr.Size,
+			modTime: hdr.ModTime,
+		}
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			e.size = 0
+
+		case tar.TypeSymlink:
+			target := hdr.Linkname
+			e.size = int64(len(target))
+			e.open = func() (io.ReadCloser, error) {
+				return io.NopCloser(strings.NewReader(target)), nil
+			}
+
+		case tar.TypeLink:
+			e.mode &^= fs.ModeType
+			e.hardLink = hdr.Linkname
+
+		case tar.TypeReg, tar.TypeRegA, tar.TypeGNUSparse:
+			if rs != nil && hdr.Typeflag != tar.TypeGNUSparse && !isPAXSparse(hdr) {
+				// the contents are right after the header
+				offset, err := rs.Seek(0, io.SeekCurrent)
+				if err != nil {
+					return nil, err
+				}
+				section := io.NewSectionReader(rs, offset, hdr.Size)
+				e.open = func() (io.ReadCloser, error) {
+					return io.NopCloser(io.NewSectionReader(section, 0, section.Size())), nil
+				}
+				break
+			}
+
+			data, err := io.ReadAll(tr)
+			if err != nil {
+				return nil, err
+			}
+			e.size = int64(len(data))
+			e.open = func() (io.ReadCloser, error) {
+				return io.NopCloser(bytes.NewReader(data)), nil
+			}
+
+		default:
+			// devices, fifos, and the like have no contents
+			e.size = 0
+		}
+		f.add(hdr.Name, e)
+	}
+	f.finish()
+	return f, nil
+}
+
+func isGzip(magic []byte) bool {
+	return len(magic) >= 2 && magic[0] == 0x1f && magic[1] == 0x8b
+}
+
+// Returns true if the header is for a sparse file in one of the PAX formats,
+// whose contents are not stored as-is
+func isPAXSparse(hdr *tar.Header) bool {
+	for k := range hdr.PAXRecords {
+		if strings.HasPrefix(k, "GNU.sparse.") {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,41 @@
+package archive
+
+import (
+	"archive/zip"
+	"io"
+	"io/fs"
+	"strings"
+)
+
+// NewZipFS indexes the zip archive in `r`, which is `size` bytes long, and
+// returns an FS for it. Contents are read from `r` when files are opened, so
+// `r` must not be closed until the FS is no longer needed.
+//
+// Unlike *zip.Reader, the FS reports every implicit directory, can open
+// entries whose names start with `/` or `./`, and follows symlinks.
+func NewZipFS(r io.ReaderAt, size int64) (*FS, error) {
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, err
+	}
+
+	f := newFS()
+	for _, zf := range zr.File {
+		e := &entry{
+			mode:    zf.Mode(),
+			size:    int64(zf.UncompressedSize64),
+			modTime: zf.Modified,
+		}
+		if strings.HasSuffix(zf.Name, "/") {
+			e.mode |= fs.ModeDir
+		}
+		if e.IsDir() {
+			e.size = 0
+		} else {
+			e.open = zf.Open
+		}
+		f.add(zf.Name, e)
+	}
+	f.finish()
+	return f, nil
+}
@@ -0,0 +1,145 @@
+package doublestar
+
+import (
+	"io/fs"
+	"path"
+	"strings"
+	"sync"
+)
+
+// CachingFS wraps an fs.FS, memoizing the results of ReadDir and Stat for the
+// lifetime of the CachingFS. Because Glob, GlobWalk, and friends read the file
+// system with fs.ReadDir and fs.Stat, passing the same CachingFS to several
+// calls means each directory is only read once:
+//
+//	cfs := doublestar.NewCachingFS(os.DirFS("."))
+//	goFiles, err := doublestar.Glob(cfs, "**/*.go")
+//	testFiles, err := doublestar.Glob(cfs, "**/*_test.go")
+//
+// Errors are memoized, too. Nothing is ever evicted: use Invalidate to discard
+// results for paths that may have changed. A CachingFS is safe for concurrent
+// use by multiple goroutines, such as parallel calls to Glob.
+//
+// Open is passed through to the underlying fs.FS and is not cached.
+type CachingFS struct {
+	fsys fs.FS
+
+	mu    sync.RWMutex
+	gen   uint64
+	dirs  map[string]cachedDir
+	infos map[string]cachedInfo
+}
+
+type cachedDir struct {
+	entries []fs.DirEntry
+	err     error
+}
+
+type cachedInfo struct {
+	info fs.FileInfo
+	err  error
+}
+
+// NewCachingFS returns a CachingFS that caches the results of ReadDir and
+// Stat on `fsys`.
+func NewCachingFS(fsys fs.FS) *CachingFS {
+	return &CachingFS{
+		fsys:  fsys,
+		dirs:  make(map[string]cachedDir),
+		infos: make(map[string]cachedInfo),
+	}
+}
+
+// Open opens the named file in the underlying fs.FS. The result is not
+// cached.
+func (c *CachingFS) Open(name string) (fs.File, error) {
+	return c.fsys.Open(name)
+}
+
+// ReadDir implements fs.ReadDirFS. The first call for a directory reads it
+// from the underlying fs.FS with fs.ReadDir; later calls return the same
+// entries (in a new slice, which the caller may modify) until the directory is
+// invalidated.
+func (c *CachingFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	c.mu.RLock()
+	d, ok := c.dirs[name]
+	gen := c.gen
+	c.mu.RUnlock()
+
+	if !ok {
+		d.entries, d.err = fs.ReadDir(c.fsys, name)
+		c.store(gen, func() { c.dirs[name] = d })
+	}
+
+	if d.entries == nil {
+		return nil, d.err
+	}
+	entries := make([]fs.DirEntry, len(d.entries))
+	copy(entries, d.entries)
+	return entries, d.err
+}
+
+// Stat implements fs.StatFS. The first call for a path runs fs.Stat on the
+// underlying fs.FS; later calls return the same result until the path is
+// invalidated.
+func (c *CachingFS) Stat(name string) (fs.FileInfo, error) {
+	c.mu.RLock()
+	i, ok := c.infos[name]
+	gen := c.gen
+	c.mu.RUnlock()
+
+	if !ok {
+		i.info, i.err = fs.Stat(c.fsys, name)
+		c.store(gen, func() { c.infos[name] = i })
+	}
+	return i.info, i.err
+}
+
+// Invalidate discards cached results for `prefix` and every path below it, as
+// well as the cached listing of its parent directory, since that listing
+// includes an entry for `prefix`. For example, if a file is created at
+// `a/b/c.txt`, Invalidate("a/b/c.txt") ensures the next call to ReadDir("a/b")
+// will find it. Invalidate(".") discards everything.
+func (c *CachingFS) Invalidate(prefix string) {
+	prefix = path.Clean(prefix)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// any reads that are in flight may have started before the file system
+	// changed, so their results must not be stored
+	c.gen++
+
+	if prefix == "." || prefix == "/" {
+		c.dirs = make(map[string]cachedDir)
+		c.infos = make(map[string]cachedInfo)
+		return
+	}
+
+	for name := range c.dirs {
+		if hasPathPrefix(name, prefix) {
+			delete(c.dirs, name)
+		}
+	}
+	for name := range c.infos {
+		if hasPathPrefix(name, prefix) {
+			delete(c.infos, name)
+		}
+	}
+	delete(c.dirs, path.Dir(prefix))
+}
+
+// Stores a result read while the cache was at generation `gen`, unless the
+// cache has been invalidated since.
+func (c *CachingFS) store(gen uint64, set func()) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.gen == gen {
+		set()
+	}
+}
+
+// Returns true if `name` is `prefix` or a path below it.
+func hasPathPrefix(name, prefix string) bool {
+	return name == prefix || (strings.HasPrefix(name, prefix) && name[len(prefix)] == '/')
+}
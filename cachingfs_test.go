@@ -0,0 +1,136 @@
+package doublestar
+
+import (
+	"io/fs"
+	"sync"
+	"testing"
+	"testing/fstest"
+)
+
+// countingFS counts calls to ReadDir and Stat
+type countingFS struct {
+	fstest.MapFS
+
+	mu       sync.Mutex
+	readDirs map[string]int
+	stats    map[string]int
+}
+
+func newCountingFS(fsys fstest.MapFS) *countingFS {
+	return &countingFS{MapFS: fsys, readDirs: make(map[string]int), stats: make(map[string]int)}
+}
+
+func (c *countingFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	c.mu.Lock()
+	c.readDirs[name]++
+	c.mu.Unlock()
+	return c.MapFS.ReadDir(name)
+}
+
+func (c *countingFS) Stat(name string) (fs.FileInfo, error) {
+	c.mu.Lock()
+	c.stats[name]++
+	c.mu.Unlock()
+	return c.MapFS.Stat(name)
+}
+
+func TestCachingFS(t *testing.T) {
+	fsys := newCountingFS(fstest.MapFS{
+		"a/b/c.txt": {},
+		"a/d.txt":   {},
+		"e.txt":     {},
+	})
+	cfs := NewCachingFS(fsys)
+
+	for i := 0; i < 3; i++ {
+		matches, err := Glob(cfs, "**/*.txt")
+		if err != nil || !compareSlices(matches, []string{"a/b/c.txt", "a/d.txt", "e.txt"}) {
+			t.Fatalf("#%v. Glob(CachingFS, `**/*.txt`) = %v, %v", i, matches, err)
+		}
+		if _, err := Glob(cfs, "a/d.txt"); err != nil {
+			t.Fatalf("#%v. Glob(CachingFS, `a/d.txt`) error: %v", i, err)
+		}
+	}
+	for _, dir := range []string{".", "a", "a/b"} {
+		if fsys.readDirs[dir] != 1 {
+			t.Errorf("ReadDir(%#q) was called %v times want 1", dir, fsys.readDirs[dir])
+		}
+	}
+	if fsys.stats["a/d.txt"] != 1 {
+		t.Errorf("Stat(`a/d.txt`) was called %v times want 1", fsys.stats["a/d.txt"])
+	}
+
+	// a new file under `a/b` is found after invalidating it
+	fsys.MapFS["a/b/f.txt"] = &fstest.MapFile{}
+	if matches, _ := Glob(cfs, "a/b/*"); len(matches) != 1 {
+		t.Errorf("Glob(CachingFS, `a/b/*`) before Invalidate = %v want cached result", matches)
+	}
+	cfs.Invalidate("a/b/f.txt")
+	if matches, _ := Glob(cfs, "a/b/*"); !compareSlices(matches, []string{"a/b/c.txt", "a/b/f.txt"}) {
+		t.Errorf("Glob(CachingFS, `a/b/*`) after Invalidate(`a/b/f.txt`) = %v", matches)
+	}
+
+	// invalidating `a` discards `a`, `a/b`, and `.`, but not `ab`
+	cfs.ReadDir("ab")
+	fsys.readDirs = make(map[string]int)
+	cfs.Invalidate("a")
+	for _, dir := range []string{".", "a", "a/b", "ab"} {
+		cfs.ReadDir(dir)
+	}
+	expected := map[string]int{".": 1, "a": 1, "a/b": 1, "ab": 0}
+	for dir, n := range expected {
+		if fsys.readDirs[dir] != n {
+			t.Errorf("ReadDir(%#q) after Invalidate(`a`) was called %v times want %v", dir, fsys.readDirs[dir], n)
+		}
+	}
+
+	// invalidating `.` discards everything
+	fsys.stats = make(map[string]int)
+	cfs.Invalidate(".")
+	cfs.Stat("a/d.txt")
+	if fsys.stats["a/d.txt"] != 1 {
+		t.Errorf("Stat(`a/d.txt`) after Invalidate(`.`) was called %v times want 1", fsys.stats["a/d.txt"])
+	}
+
+	// errors are cached
+	fsys.readDirs = make(map[string]int)
+	cfs.ReadDir("nope")
+	if _, err := cfs.ReadDir("nope"); err == nil || fsys.readDirs["nope"] != 1 {
+		t.Errorf("ReadDir(`nope`) = %v, called %v times want an error, called once", err, fsys.readDirs["nope"])
+	}
+
+	// callers may modify the returned slice
+	entries, _ := cfs.ReadDir("a")
+	entries[0] = nil
+	if entries, _ = cfs.ReadDir("a"); entries[0] == nil {
+		t.Errorf("ReadDir(`a`) returned a slice shared with the cache")
+	}
+}
+
+func TestCachingFSConcurrent(t *testing.T) {
+	fsys := newCountingFS(fstest.MapFS{
+		"a/b/c.txt": {},
+		"a/d.txt":   {},
+		"e/f.txt":   {},
+	})
+	cfs := NewCachingFS(fsys)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				matches, err := Glob(cfs, "**/*.txt")
+				if err != nil || len(matches) != 3 {
+					t.Errorf("Glob(CachingFS, `**/*.txt`) = %v, %v", matches, err)
+					return
+				}
+				if i == 0 && j%10 == 0 {
+					cfs.Invalidate("a")
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+}
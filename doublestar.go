@@ -2,7 +2,9 @@ package doublestar
 
 import (
 	"errors"
+	"io/fs"
 	"path"
+	"strings"
 )
 
 // ErrBadPattern indicates a pattern was malformed.
@@ -11,3 +13,64 @@ var ErrBadPattern = path.ErrBadPattern
 // ErrPatternNotExist indicates that the pattern passed to Glob, GlobWalk, or
 // FilepathGlob references a path that does not exist.
 var ErrPatternNotExist = errors.New("pattern does not exist")
+
+// IOErrors is returned by Glob, GlobWalk, and friends when WithCollectErrors
+// is passed and one or more I/O errors were encountered while globbing. Each
+// error is a *fs.PathError. errors.Is and errors.As report whether any of the
+// errors match, so, for example, errors.Is(err, fs.ErrPermission) is true if
+// any path could not be read because of its permissions.
+type IOErrors struct {
+	Errors []error
+}
+
+// Error returns the messages of all of the errors, separated by newlines.
+func (e *IOErrors) Error() string {
+	msgs := make([]string, len(e.Errors))
+	for i, err := range e.Errors {
+		msgs[i] = err.Error()
+	}
+	return strings.Join(msgs, "\n")
+}
+
+// Unwrap returns the errors. It is used by errors.Is and errors.As in Go 1.20
+// and later.
+func (e *IOErrors) Unwrap() []error {
+	return e.Errors
+}
+
+// Is returns true if any of the errors match target. It is used by errors.Is
+// in versions of Go before 1.20.
+func (e *IOErrors) Is(target error) bool {
+	for _, err := range e.Errors {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// As finds the first error that matches target, and if so, sets target to
+// that error and returns true. It is used by errors.As in versions of Go
+// before 1.20.
+func (e *IOErrors) As(target interface{}) bool {
+	for _, err := range e.Errors {
+		if errors.As(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// Replaces the path of every *fs.PathError with the result of `fn`. Used to
+// convert paths relative to an fs.FS into OS paths.
+func (e *IOErrors) rebase(fn func(string) string) {
+	for i, err := range e.Errors {
+		if pe, ok := err.(*fs.PathError); ok {
+			e.Errors[i] = &fs.PathError{Op: pe.Op, Path: fn(pe.Path), Err: pe.Err}
+		}
+	}
+}
+
+// errLimitReached is used internally to stop globbing once the limit set by
+// WithLimit has been reached. It is never returned to the caller.
+var errLimitReached = errors.New("limit reached")
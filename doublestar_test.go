@@ -1,6 +1,8 @@
 package doublestar
 
 import (
+	"context"
+	"errors"
 	"io/fs"
 	"log"
 	"os"
@@ -9,6 +11,8 @@ import (
 	"runtime"
 	"strings"
 	"testing"
+	"testing/fstest"
+	"unicode/utf8"
 )
 
 type MatchTest struct {
@@ -462,6 +466,10 @@ func TestGlobWithAllOptions(t *testing.T) {
 	doGlobTest(t, WithCaseInsensitive(), WithFailOnIOErrors(), WithFailOnPatternNotExist(), WithFilesOnly(), WithNoFollow())
 }
 
+func TestGlobWithBreadthFirstTraversal(t *testing.T) {
+	doGlobTest(t, WithBreadthFirstTraversal())
+}
+
 func doGlobTest(t *testing.T, opts ...GlobOption) {
 	glob := newGlob(opts...)
 	fsys := os.DirFS("test")
@@ -484,6 +492,10 @@ func testGlobWith(t *testing.T, idx int, tt MatchTest, g *glob, opts []GlobOptio
 	if len(opts) == 0 {
 		testStandardGlob(t, idx, "Glob", tt, fsys, matches, err)
 	}
+
+	// a Lister adapted from the fs.FS must behave identically
+	matches, err = GlobLister(context.Background(), FSLister(fsys), tt.pattern, opts...)
+	verifyGlobResults(t, idx, "GlobLister", tt, g, fsys, matches, err)
 }
 
 func TestGlobWalk(t *testing.T) {
@@ -510,6 +522,10 @@ func TestGlobWalkWithAllOptions(t *testing.T) {
 	doGlobWalkTest(t, WithFailOnIOErrors(), WithFailOnPatternNotExist(), WithFilesOnly(), WithNoFollow())
 }
 
+func TestGlobWalkWithBreadthFirstTraversal(t *testing.T) {
+	doGlobWalkTest(t, WithBreadthFirstTraversal())
+}
+
 func doGlobWalkTest(t *testing.T, opts ...GlobOption) {
 	glob := newGlob(opts...)
 	fsys := os.DirFS("test")
@@ -536,6 +552,556 @@ func testGlobWalkWith(t *testing.T, idx int, tt MatchTest, g *glob, opts []GlobO
 	if len(opts) == 0 {
 		testStandardGlob(t, idx, "GlobWalk", tt, fsys, matches, err)
 	}
+
+	matches = nil
+	err = GlobWalkLister(context.Background(), FSLister(fsys), tt.pattern, func(p string, d fs.DirEntry) error {
+		matches = append(matches, p)
+		return nil
+	}, opts...)
+	verifyGlobResults(t, idx, "GlobWalkLister", tt, g, fsys, matches, err)
+}
+
+func TestGlobWalkDir(t *testing.T) {
+	doGlobWalkDirTest(t)
+}
+
+func TestGlobWalkDirWithAllOptions(t *testing.T) {
+	doGlobWalkDirTest(t, WithFailOnIOErrors(), WithFailOnPatternNotExist(), WithFilesOnly(), WithNoFollow())
+}
+
+func doGlobWalkDirTest(t *testing.T, opts ...GlobOption) {
+	glob := newGlob(opts...)
+	fsys := os.DirFS("test")
+	for idx, tt := range matchTests {
+		if tt.testOnDisk && (!tt.caseSensitive || fsIsCaseSensitive) {
+			testGlobWalkDirWith(t, idx, tt, glob, opts, fsys)
+		}
+	}
+}
+
+func testGlobWalkDirWith(t *testing.T, idx int, tt MatchTest, g *glob, opts []GlobOption, fsys fs.FS) {
+	defer func() {
+		if r := recover(); r != nil {
+			t.Errorf("#%v. GlobWalkDir(%#q, %#v) panicked: %#v", idx, tt.pattern, opts, r)
+		}
+	}()
+
+	var matches []string
+	active := make(map[string]bool)
+	err := GlobWalkDir(fsys, tt.pattern, GlobWalkOptions{
+		Enter: func(dir string) error {
+			if active[dir] {
+				t.Errorf("#%v. GlobWalkDir(%#q, %#v) entered %#q twice", idx, tt.pattern, g, dir)
+			}
+			active[dir] = true
+			return nil
+		},
+		Leave: func(dir string) {
+			if !active[dir] {
+				t.Errorf("#%v. GlobWalkDir(%#q, %#v) left %#q without entering it", idx, tt.pattern, g, dir)
+			}
+			delete(active, dir)
+		},
+		Match: func(p string, d fs.DirEntry) error {
+			matches = append(matches, p)
+			return nil
+		},
+	}, opts...)
+	verifyGlobResults(t, idx, "GlobWalkDir", tt, g, fsys, matches, err)
+	if err == nil && len(active) != 0 {
+		t.Errorf("#%v. GlobWalkDir(%#q, %#v) never left %v", idx, tt.pattern, g, active)
+	}
+}
+
+var walkDirTestFS = fstest.MapFS{
+	"a/.nobackup": {},
+	"a/x.txt":     {},
+	"a/sub/y.txt": {},
+	"b/z.txt":     {},
+	"b/sub/w.txt": {},
+	"c.txt":       {},
+}
+
+func TestGlobWalkDirPruning(t *testing.T) {
+	tests := []struct {
+		pattern  string
+		expected []string
+	}{
+		{"**/*.txt", []string{"b/z.txt", "b/sub/w.txt", "c.txt"}},
+		{"*/*.txt", []string{"b/z.txt"}},
+		{"**/sub/*.txt", []string{"b/sub/w.txt"}},
+		{"{a,b}/**/*.txt", []string{"b/z.txt", "b/sub/w.txt"}},
+		{"**", []string{".", "a", "b", "b/sub", "b/sub/w.txt", "b/z.txt", "c.txt"}},
+		{"a/x.txt", []string{"a/x.txt"}},
+	}
+
+	enter := func(dir string) error {
+		// prune directories containing a `.nobackup` marker
+		if _, err := fs.Stat(walkDirTestFS, path.Join(dir, ".nobackup")); err == nil {
+			return SkipDir
+		}
+		return nil
+	}
+
+	for idx, tt := range tests {
+		for _, opts := range [][]GlobOption{nil, {WithBreadthFirstTraversal()}, {WithSortOrder(SortLexical)}} {
+			var matches []string
+			err := GlobWalkDir(walkDirTestFS, tt.pattern, GlobWalkOptions{
+				Enter: enter,
+				Match: func(p string, d fs.DirEntry) error {
+					matches = append(matches, p)
+					return nil
+				},
+			}, opts...)
+			if err != nil || !compareSlices(matches, tt.expected) {
+				t.Errorf("#%v. GlobWalkDir(%#q, %#v) = %#v, %v want %#v, nil", idx, tt.pattern, newGlob(opts...), matches, err, tt.expected)
+			}
+		}
+	}
+}
+
+func TestGlobWalkDirEvents(t *testing.T) {
+	tests := []struct {
+		opts     []GlobOption
+		expected []string
+	}{
+		{nil, []string{
+			"enter .", "match c.txt",
+			"enter a", "match a/x.txt", "enter a/sub", "match a/sub/y.txt", "leave a/sub", "leave a",
+			"enter b", "match b/z.txt", "enter b/sub", "match b/sub/w.txt", "leave b/sub", "leave b",
+			"leave .",
+		}},
+		{[]GlobOption{WithBreadthFirstTraversal()}, []string{
+			"enter .", "match c.txt",
+			"enter a", "match a/x.txt", "enter b", "match b/z.txt", "leave .",
+			"enter a/sub", "match a/sub/y.txt", "leave a",
+			"enter b/sub", "match b/sub/w.txt", "leave b",
+			"leave a/sub", "leave b/sub",
+		}},
+	}
+
+	for idx, tt := range tests {
+		var events []string
+		err := GlobWalkDir(walkDirTestFS, "**/*.txt", GlobWalkOptions{
+			Enter: func(dir string) error {
+				events = append(events, "enter "+dir)
+				return nil
+			},
+			Leave: func(dir string) {
+				events = append(events, "leave "+dir)
+			},
+			Match: func(p string, d fs.DirEntry) error {
+				events = append(events, "match "+p)
+				return nil
+			},
+		}, tt.opts...)
+		if err != nil || !equalSlices(events, tt.expected) {
+			t.Errorf("#%v. GlobWalkDir(`**/*.txt`, %#v) = %#v, %v want %#v, nil", idx, newGlob(tt.opts...), events, err, tt.expected)
+		}
+	}
+
+	expectedErr := errors.New("stop")
+	var left []string
+	err := GlobWalkDir(walkDirTestFS, "**/*.txt", GlobWalkOptions{
+		Enter: func(dir string) error {
+			if dir == "b" {
+				return expectedErr
+			}
+			return nil
+		},
+		Leave: func(dir string) {
+			left = append(left, dir)
+		},
+	})
+	if err != expectedErr || !equalSlices(left, []string{"a/sub", "a"}) {
+		t.Errorf("GlobWalkDir(`**/*.txt`) with an Enter error = %v, left %#v want %v, left [a/sub a]", err, left, expectedErr)
+	}
+}
+
+func TestGlobEntries(t *testing.T) {
+	doGlobEntriesTest(t)
+}
+
+func TestGlobEntriesWithAllOptions(t *testing.T) {
+	doGlobEntriesTest(t, WithFailOnIOErrors(), WithFailOnPatternNotExist(), WithFilesOnly(), WithNoFollow())
+}
+
+func doGlobEntriesTest(t *testing.T, opts ...GlobOption) {
+	glob := newGlob(opts...)
+	fsys := os.DirFS("test")
+	for idx, tt := range matchTests {
+		if tt.testOnDisk && (!tt.caseSensitive || fsIsCaseSensitive) {
+			testGlobEntriesWith(t, idx, tt, glob, opts, fsys)
+		}
+	}
+}
+
+func testGlobEntriesWith(t *testing.T, idx int, tt MatchTest, g *glob, opts []GlobOption, fsys fs.FS) {
+	defer func() {
+		if r := recover(); r != nil {
+			t.Errorf("#%v. GlobEntries(%#q, %#v) panicked: %#v", idx, tt.pattern, opts, r)
+		}
+	}()
+
+	entries, err := GlobEntries(fsys, tt.pattern, opts...)
+	var matches []string
+	for i := range entries {
+		matches = append(matches, entries[i].Path)
+
+		info, infoErr := entries[i].Info()
+		if infoErr != nil || info == nil {
+			t.Errorf("#%v. GlobEntries(%#q, %#v) Info() for %#q = %v, %v", idx, tt.pattern, g, entries[i].Path, info, infoErr)
+		} else if info.Name() != path.Base(entries[i].Path) {
+			t.Errorf("#%v. GlobEntries(%#q, %#v) Info().Name() = %#q, want base of %#q", idx, tt.pattern, g, info.Name(), entries[i].Path)
+		}
+	}
+	verifyGlobResults(t, idx, "GlobEntries", tt, g, fsys, matches, err)
+	if len(opts) == 0 {
+		testStandardGlob(t, idx, "GlobEntries", tt, fsys, matches, err)
+	}
+}
+
+var errTest = errors.New("test error")
+
+// errFS wraps a fstest.MapFS. Reading a directory in readDirErrs returns its
+// first entry and an error, and running Stat on a path in statErrs fails.
+type errFS struct {
+	fstest.MapFS
+	readDirErrs map[string]bool
+	statErrs    map[string]bool
+}
+
+func (f errFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	entries, err := f.MapFS.ReadDir(name)
+	if err == nil && f.readDirErrs[name] {
+		if len(entries) > 1 {
+			entries = entries[:1]
+		}
+		return entries, &fs.PathError{Op: "readdir", Path: "/root/" + name, Err: errTest}
+	}
+	return entries, err
+}
+
+func (f errFS) Stat(name string) (fs.FileInfo, error) {
+	if f.statErrs[name] {
+		return nil, &fs.PathError{Op: "stat", Path: "/root/" + name, Err: errTest}
+	}
+	return f.MapFS.Stat(name)
+}
+
+func TestGlobWithErrorHandler(t *testing.T) {
+	fsys := errFS{
+		MapFS: fstest.MapFS{
+			"a/p.txt": {},
+			"a/q.txt": {},
+			"b/x.txt": {},
+			"c.txt":   {},
+			"link":    {Mode: fs.ModeSymlink},
+		},
+		readDirErrs: map[string]bool{"a": true},
+		statErrs:    map[string]bool{"link": true},
+	}
+	errAbort := errors.New("abort")
+
+	tests := []struct {
+		pattern     string
+		result      error
+		expected    []string
+		expectedErr error
+	}{
+		{"**/*.txt", nil, []string{"a/p.txt", "b/x.txt", "c.txt"}, nil},
+		{"**/*.txt", SkipDir, []string{"b/x.txt", "c.txt"}, nil},
+		{"**/*.txt", errAbort, nil, errAbort},
+		{"*/*.txt", nil, []string{"a/p.txt", "b/x.txt"}, nil},
+		{"*/*.txt", SkipDir, []string{"b/x.txt"}, nil},
+		{"*/*.txt", errAbort, nil, errAbort},
+		{"a/*", nil, []string{"a/p.txt"}, nil},
+		{"a/*", SkipDir, nil, nil},
+	}
+
+	for idx, tt := range tests {
+		reported := make(map[string]bool)
+		handler := WithErrorHandler(func(p string, err error) error {
+			var pe *fs.PathError
+			if !errors.As(err, &pe) || pe.Path != p || !errors.Is(err, errTest) {
+				t.Errorf("#%v. WithErrorHandler got %#q, %#v want a *fs.PathError for %#q wrapping errTest", idx, p, err, p)
+			}
+			reported[p] = true
+			return tt.result
+		})
+
+		matches, err := Glob(fsys, tt.pattern, handler)
+		if err != tt.expectedErr || (err == nil && !compareSlices(matches, tt.expected)) {
+			t.Errorf("#%v. Glob(%#q) with handler returning %v = %#v, %v want %#v, %v", idx, tt.pattern, tt.result, matches, err, tt.expected, tt.expectedErr)
+		}
+
+		matches = nil
+		err = GlobWalk(fsys, tt.pattern, func(p string, d fs.DirEntry) error {
+			matches = append(matches, p)
+			return nil
+		}, handler)
+		if err != tt.expectedErr || (err == nil && !compareSlices(matches, tt.expected)) {
+			t.Errorf("#%v. GlobWalk(%#q) with handler returning %v = %#v, %v want %#v, %v", idx, tt.pattern, tt.result, matches, err, tt.expected, tt.expectedErr)
+		}
+
+		if !reported["a"] {
+			t.Errorf("#%v. Glob(%#q) did not report an error for `a`: %v", idx, tt.pattern, reported)
+		}
+		if tt.result != errAbort && tt.pattern != "a/*" && !reported["link"] {
+			t.Errorf("#%v. Glob(%#q) did not report an error for `link`: %v", idx, tt.pattern, reported)
+		}
+	}
+
+	// without a handler, WithFailOnIOErrors returns the same *fs.PathError
+	_, err := Glob(fsys, "a/*", WithFailOnIOErrors())
+	var pe *fs.PathError
+	if !errors.As(err, &pe) || pe.Path != "a" || !errors.Is(err, errTest) {
+		t.Errorf("Glob(`a/*`, WithFailOnIOErrors()) = %#v want a *fs.PathError for `a` wrapping errTest", err)
+	}
+
+	// the handler takes precedence over WithFailOnIOErrors
+	matches, err := Glob(fsys, "a/*", WithFailOnIOErrors(), WithErrorHandler(func(string, error) error { return nil }))
+	if err != nil || !compareSlices(matches, []string{"a/p.txt"}) {
+		t.Errorf("Glob(`a/*`, WithFailOnIOErrors(), WithErrorHandler(...)) = %#v, %v want [a/p.txt], nil", matches, err)
+	}
+}
+
+func TestGlobWithCollectErrors(t *testing.T) {
+	fsys := errFS{
+		MapFS: fstest.MapFS{
+			"a/p.txt": {},
+			"b/x.txt": {},
+			"c.txt":   {},
+			"link":    {Mode: fs.ModeSymlink},
+		},
+		readDirErrs: map[string]bool{"a": true},
+		statErrs:    map[string]bool{"link": true},
+	}
+
+	tests := []struct {
+		pattern  string
+		expected []string
+		errPaths []string
+	}{
+		{"**/*.txt", []string{"b/x.txt", "c.txt"}, []string{"a", "link"}},
+		{"*/*.txt", []string{"b/x.txt"}, []string{"a", "link"}},
+		{"a/*", nil, []string{"a"}},
+		{"b/*", []string{"b/x.txt"}, nil},
+	}
+
+	for idx, tt := range tests {
+		var walked []string
+		walkErr := GlobWalk(fsys, tt.pattern, func(p string, d fs.DirEntry) error {
+			walked = append(walked, p)
+			return nil
+		}, WithCollectErrors(), WithFailOnIOErrors())
+		matches, err := Glob(fsys, tt.pattern, WithCollectErrors(), WithFailOnIOErrors())
+
+		for _, r := range []struct {
+			fn      string
+			matches []string
+			err     error
+		}{{"Glob", matches, err}, {"GlobWalk", walked, walkErr}} {
+			if !compareSlices(r.matches, tt.expected) {
+				t.Errorf("#%v. %v(%#q, WithCollectErrors()) = %#v want %#v", idx, r.fn, tt.pattern, r.matches, tt.expected)
+			}
+
+			if tt.errPaths == nil {
+				if r.err != nil {
+					t.Errorf("#%v. %v(%#q, WithCollectErrors()) error = %v want nil", idx, r.fn, tt.pattern, r.err)
+				}
+				continue
+			}
+
+			var ioErrs *IOErrors
+			if !errors.As(r.err, &ioErrs) {
+				t.Errorf("#%v. %v(%#q, WithCollectErrors()) error = %#v want *IOErrors", idx, r.fn, tt.pattern, r.err)
+				continue
+			}
+			var paths []string
+			for _, e := range ioErrs.Errors {
+				if pe, ok := e.(*fs.PathError); ok {
+					paths = append(paths, pe.Path)
+				} else {
+					t.Errorf("#%v. %v(%#q, WithCollectErrors()) collected %#v want a *fs.PathError", idx, r.fn, tt.pattern, e)
+				}
+			}
+			if !compareSlices(paths, tt.errPaths) {
+				t.Errorf("#%v. %v(%#q, WithCollectErrors()) collected errors for %#v want %#v", idx, r.fn, tt.pattern, paths, tt.errPaths)
+			}
+
+			var pe *fs.PathError
+			if !errors.Is(r.err, errTest) || !errors.As(r.err, &pe) || errors.Is(r.err, fs.ErrNotExist) {
+				t.Errorf("#%v. %v(%#q, WithCollectErrors()) error does not unwrap correctly: %#v", idx, r.fn, tt.pattern, r.err)
+			}
+		}
+	}
+
+	// errors the handler chooses to abort on are not collected
+	errAbort := errors.New("abort")
+	_, err := Glob(fsys, "**/*.txt", WithCollectErrors(), WithErrorHandler(func(p string, err error) error {
+		if p == "a" {
+			return errAbort
+		}
+		return nil
+	}))
+	if err != errAbort {
+		t.Errorf("Glob(`**/*.txt`, WithCollectErrors(), WithErrorHandler(...)) = %v want %v", err, errAbort)
+	}
+
+	if !onWindows {
+		if _, err := os.ReadDir(filepath.Join("test", "nopermission")); err != nil {
+			// only possible to test if we aren't running as root
+			matches, err := FilepathGlob(filepath.Join("test", "**", "file"), WithCollectErrors())
+			if !errors.Is(err, fs.ErrPermission) || !strings.Contains(err.Error(), filepath.Join("test", "nopermission")) {
+				t.Errorf("FilepathGlob(`test/**/file`, WithCollectErrors()) error = %v want fs.ErrPermission for test/nopermission", err)
+			}
+			if len(matches) == 0 {
+				t.Errorf("FilepathGlob(`test/**/file`, WithCollectErrors()) returned no matches")
+			}
+		}
+	}
+}
+
+// countingDirEntry counts the number of times Info() is called
+type countingDirEntry struct {
+	fs.DirEntry
+	infoCalls int
+}
+
+func (d *countingDirEntry) Info() (fs.FileInfo, error) {
+	d.infoCalls++
+	return d.DirEntry.Info()
+}
+
+func TestGlobMatchInfoIsCached(t *testing.T) {
+	fsys := fstest.MapFS{"a/b": {Data: []byte("hello")}}
+	entries, err := GlobEntries(fsys, "a/*")
+	if err != nil || len(entries) != 1 {
+		t.Fatalf("GlobEntries(`a/*`) = %#v, %v want 1 result", entries, err)
+	}
+
+	// the cache must survive ranging over the matches, which copies elements
+	entry := &countingDirEntry{DirEntry: entries[0].Entry}
+	entries[0].Entry = entry
+	for i := 0; i < 2; i++ {
+		for _, m := range entries {
+			info, err := m.Info()
+			if err != nil || info.Size() != 5 {
+				t.Errorf("GlobMatch.Info() = %v, %v want size 5", info, err)
+			}
+		}
+	}
+	if entry.infoCalls != 1 {
+		t.Errorf("GlobMatch.Info() called DirEntry.Info() %v times, want 1", entry.infoCalls)
+	}
+}
+
+var limitTestFS = fstest.MapFS{
+	"a/b/c.txt": {},
+	"a/d.txt":   {},
+	"e.txt":     {},
+	"f/g.txt":   {},
+	"f/h.txt":   {},
+}
+
+func TestGlobWithLimit(t *testing.T) {
+	all, _ := Glob(limitTestFS, "**/*.txt")
+	for _, n := range []int{1, 2, len(all), len(all) + 1} {
+		matches, err := Glob(limitTestFS, "**/*.txt", WithLimit(n))
+		expected := n
+		if expected > len(all) {
+			expected = len(all)
+		}
+		if err != nil || len(matches) != expected || !isSubset(matches, all) {
+			t.Errorf("Glob(`**/*.txt`, WithLimit(%v)) = %#v, %v want %v of %#v", n, matches, err, expected, all)
+		}
+
+		calls := 0
+		err = GlobWalk(limitTestFS, "**/*.txt", func(p string, d fs.DirEntry) error {
+			calls++
+			return nil
+		}, WithLimit(n))
+		if err != nil || calls != expected {
+			t.Errorf("GlobWalk(`**/*.txt`, WithLimit(%v)) called fn %v times, %v want %v", n, calls, err, expected)
+		}
+
+		entries, err := GlobEntries(limitTestFS, "**/*.txt", WithLimit(n))
+		if err != nil || len(entries) != expected {
+			t.Errorf("GlobEntries(`**/*.txt`, WithLimit(%v)) = %v results, %v want %v", n, len(entries), err, expected)
+		}
+	}
+
+	matches, err := Glob(limitTestFS, "**/*.txt", WithLimit(2), WithSortOrder(SortBreadthFirst))
+	expected := []string{"e.txt", "a/d.txt"}
+	if err != nil || !equalSlices(matches, expected) {
+		t.Errorf("Glob(`**/*.txt`, WithLimit(2), WithSortOrder(SortBreadthFirst)) = %#v, %v want %#v, nil", matches, err, expected)
+	}
+
+	// a match counts toward the limit even if the callback skips its directory
+	var walked []string
+	err = GlobWalk(limitTestFS, "**", func(p string, d fs.DirEntry) error {
+		walked = append(walked, p)
+		if p == "a" {
+			return SkipDir
+		}
+		return nil
+	}, WithLimit(3), WithSortOrder(SortLexical))
+	expected = []string{".", "a", "e.txt"}
+	if err != nil || !equalSlices(walked, expected) {
+		t.Errorf("GlobWalk(`**`, WithLimit(3)) returning SkipDir = %#v, %v want %#v, nil", walked, err, expected)
+	}
+
+	filepathMatches, err := FilepathGlob(filepath.Join("test", "**"), WithLimit(3))
+	if err != nil || len(filepathMatches) != 3 {
+		t.Errorf("FilepathGlob(`test/**`, WithLimit(3)) = %#v, %v want 3 results", filepathMatches, err)
+	}
+}
+
+func TestGlobFirst(t *testing.T) {
+	tests := []struct {
+		pattern  string
+		opts     []GlobOption
+		expected string
+		found    bool
+		err      error
+	}{
+		{"e.txt", nil, "e.txt", true, nil},
+		{"f/*.txt", []GlobOption{WithSortOrder(SortLexical)}, "f/g.txt", true, nil},
+		{"**/c.txt", nil, "a/b/c.txt", true, nil},
+		{"**/*.go", nil, "", false, nil},
+		{"nope/*", nil, "", false, nil},
+		{"nope/*", []GlobOption{WithFailOnPatternNotExist()}, "", false, ErrPatternNotExist},
+		{"[", nil, "", false, ErrBadPattern},
+	}
+
+	for idx, tt := range tests {
+		match, found, err := GlobFirst(limitTestFS, tt.pattern, tt.opts...)
+		if match != tt.expected || found != tt.found || err != tt.err {
+			t.Errorf("#%v. GlobFirst(%#q) = %#q, %v, %v want %#q, %v, %v", idx, tt.pattern, match, found, err, tt.expected, tt.found, tt.err)
+		}
+
+		found, err = GlobExists(limitTestFS, tt.pattern, tt.opts...)
+		if found != tt.found || err != tt.err {
+			t.Errorf("#%v. GlobExists(%#q) = %v, %v want %v, %v", idx, tt.pattern, found, err, tt.found, tt.err)
+		}
+	}
+}
+
+func isSubset(a, b []string) bool {
+	for _, s := range a {
+		found := false
+		for _, t := range b {
+			if s == t {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
 }
 
 func testStandardGlob(t *testing.T, idx int, fn string, tt MatchTest, fsys fs.FS, matches []string, err error) {
@@ -681,6 +1247,526 @@ func TestGlobSorted(t *testing.T) {
 	}
 }
 
+var skipHiddenMatchTests = []struct {
+	pattern, testPath string
+	shouldMatch       bool
+}{
+	{"*", ".a", false},
+	{".*", ".a", true},
+	{"\\.*", ".a", true},
+	{"?a", ".a", false},
+	{"[.]a", ".a", false},
+	{"*.yaml", ".yaml", false},
+	{"*.yaml", "a.yaml", true},
+	{"a*", "a.b", true},
+	{"a/*", "a/.b", false},
+	{"a/.*", "a/.b", true},
+	{"{.a,b}", ".a", true},
+	{"*{.a,b}", ".a", false},
+	{"**", ".a", false},
+	{"**", "a/.b", false},
+	{"**", "a/b", true},
+	{"a/**", "a/.b/c", false},
+	{"**/*.yaml", ".github/a.yaml", false},
+	{"**/*.yaml", "a/b.yaml", true},
+	{"**/.github/*.yaml", ".github/a.yaml", true},
+	{"**/.github/*.yaml", "a/.github/a.yaml", true},
+	{".github/**/*.yaml", ".github/a/b.yaml", true},
+	{".github/**/*.yaml", ".github/.a/b.yaml", false},
+}
+
+func TestMatchWithSkipHidden(t *testing.T) {
+	for idx, tt := range skipHiddenMatchTests {
+		ok, err := MatchWithOptions(tt.pattern, tt.testPath, WithSkipHidden())
+		if ok != tt.shouldMatch || err != nil {
+			t.Errorf("#%v. MatchWithOptions(%#q, %#q, WithSkipHidden) = %v, %v want %v, nil", idx, tt.pattern, tt.testPath, ok, err, tt.shouldMatch)
+		}
+
+		ok, err = Match(tt.pattern, tt.testPath)
+		if !ok || err != nil {
+			t.Errorf("#%v. Match(%#q, %#q) = %v, %v want true, nil", idx, tt.pattern, tt.testPath, ok, err)
+		}
+	}
+}
+
+func TestMatchWithOptions(t *testing.T) {
+	tests := []struct {
+		pattern, testPath string
+		opts              []MatchOption
+		shouldMatch       bool
+		expectedErr       error
+	}{
+		{"a/*/C", "a/b/c", nil, false, nil},
+		{"a/*/C", "a/b/c", []MatchOption{WithCaseInsensitive()}, true, nil},
+		{"a.*.c", "a.b.c", []MatchOption{WithSeparator('.')}, true, nil},
+		{"a.*.c", "a.b.b.c", []MatchOption{WithSeparator('.')}, false, nil},
+		{"a.**.c", "a.b.b.c", []MatchOption{WithSeparator('.')}, true, nil},
+		{"a:*", "a:b/c", []MatchOption{WithSeparator(':')}, true, nil},
+		{"a→*→c", "a→b→c", []MatchOption{WithSeparator('→')}, true, nil},
+		{"a→**→c", "a→b→b→c", []MatchOption{WithSeparator('→')}, true, nil},
+		{"a→*", "a→b→c", []MatchOption{WithSeparator('→')}, false, nil},
+		{"a\\*", "a\\b", []MatchOption{WithSeparator('\\')}, true, nil},
+		{"a/**.txt", "a/b.txt", nil, true, nil},
+		{"a/**.txt", "a/b.txt", []MatchOption{WithStrictDoubleStar()}, false, ErrBadPattern},
+		{"a**/b", "a/b", []MatchOption{WithStrictDoubleStar()}, false, ErrBadPattern},
+		{"a/***/b", "a/b", []MatchOption{WithStrictDoubleStar()}, false, ErrBadPattern},
+		{"a/**/b", "a/x/b", []MatchOption{WithStrictDoubleStar()}, true, nil},
+		{"**", "a/b", []MatchOption{WithStrictDoubleStar()}, true, nil},
+		{"a/{**,c}/b", "a/x/b", []MatchOption{WithStrictDoubleStar()}, true, nil},
+		{"a/{c,**/d}", "a/x/d", []MatchOption{WithStrictDoubleStar()}, true, nil},
+		{"a/{c,x**}", "a/x", []MatchOption{WithStrictDoubleStar()}, false, ErrBadPattern},
+		{"a/{c,**}x", "a/x", []MatchOption{WithStrictDoubleStar()}, false, ErrBadPattern},
+		{"**{,/a}", "x/a", []MatchOption{WithStrictDoubleStar()}, true, nil},
+		{"a/**{/b,{,/c}}", "a/x", []MatchOption{WithStrictDoubleStar()}, true, nil},
+		{"a/{**{,/b}}/c", "a/x/b/c", []MatchOption{WithStrictDoubleStar()}, true, nil},
+		{"**{,x}", "x", []MatchOption{WithStrictDoubleStar()}, false, ErrBadPattern},
+		{"**{/a,}x", "x", []MatchOption{WithStrictDoubleStar()}, false, ErrBadPattern},
+		{"**{/a,{x,}}", "x", []MatchOption{WithStrictDoubleStar()}, false, ErrBadPattern},
+		{"a/[**]", "a/*", []MatchOption{WithStrictDoubleStar()}, true, nil},
+		{"a/\\**", "a/*b", []MatchOption{WithStrictDoubleStar()}, true, nil},
+		{"a.**x", "a.bx", []MatchOption{WithSeparator('.'), WithStrictDoubleStar()}, false, ErrBadPattern},
+		{"*/.B", "a/.b", []MatchOption{WithCaseInsensitive(), WithSkipHidden()}, true, nil},
+		{"a:*", "a:.b", []MatchOption{WithSeparator(':'), WithSkipHidden()}, false, nil},
+	}
+
+	for idx, tt := range tests {
+		ok, err := MatchWithOptions(tt.pattern, tt.testPath, tt.opts...)
+		if ok != tt.shouldMatch || err != tt.expectedErr {
+			t.Errorf("#%v. MatchWithOptions(%#q, %#q, %#v) = %v, %v want %v, %v", idx, tt.pattern, tt.testPath, newGlob(tt.opts...), ok, err, tt.shouldMatch, tt.expectedErr)
+		}
+
+		pattern, testPath := tt.pattern, tt.testPath
+		if newGlob(tt.opts...).separator == 0 {
+			pattern, testPath = filepath.FromSlash(pattern), filepath.FromSlash(testPath)
+			if onWindows && strings.Contains(tt.pattern, "\\") {
+				continue
+			}
+		}
+		ok, err = PathMatchWithOptions(pattern, testPath, tt.opts...)
+		if ok != tt.shouldMatch || err != tt.expectedErr {
+			t.Errorf("#%v. PathMatchWithOptions(%#q, %#q, %#v) = %v, %v want %v, %v", idx, pattern, testPath, newGlob(tt.opts...), ok, err, tt.shouldMatch, tt.expectedErr)
+		}
+	}
+}
+
+func TestMatchWithSeparator(t *testing.T) {
+	tests := []struct {
+		pattern, testPath string
+		separator         rune
+		shouldMatch       bool
+		expectedErr       error
+	}{
+		{"service.*.timeout", "service.api.timeout", '.', true, nil},
+		{"service.*.timeout", "service.api.v2.timeout", '.', false, nil},
+		{"metrics.**.p99", "metrics.http.get.p99", '.', true, nil},
+		{"metrics.**.p99", "metrics.p99", '.', true, nil},
+		{"metrics.**", "metrics", '.', true, nil},
+		{"com.example.?", "com.example.a", '.', true, nil},
+		{"com.example.?", "com.example..", '.', false, nil},
+		{"com.example.{a,b}.*", "com.example.b.Foo", '.', true, nil},
+		{"com\\.example", "com.example", '.', true, nil},
+		{"a/b.*", "a/b.c", '.', true, nil},
+		{"a/*", "a/b.c", '.', false, nil},
+		{"a:**:z", "a:b:c:z", ':', true, nil},
+		{"a:*", "a:b:c", ':', false, nil},
+		{"a\\*", "a\\b", '\\', true, nil},
+		{"a\\[", "a\\[", '\\', false, ErrBadPattern},
+		{"a*b", "ab", '*', false, ErrBadPattern},
+		{"a,b", "a,b", ',', false, ErrBadPattern},
+		{"a{b", "a{b", '{', false, ErrBadPattern},
+		{"a", "a", 0, false, ErrBadPattern},
+		{"a", "a", utf8.RuneError, false, ErrBadPattern},
+		{"service.[", "service.x", '.', false, ErrBadPattern},
+	}
+
+	for idx, tt := range tests {
+		ok, err := MatchWithSeparator(tt.pattern, tt.testPath, tt.separator)
+		if ok != tt.shouldMatch || err != tt.expectedErr {
+			t.Errorf("#%v. MatchWithSeparator(%#q, %#q, %q) = %v, %v want %v, %v", idx, tt.pattern, tt.testPath, tt.separator, ok, err, tt.shouldMatch, tt.expectedErr)
+		}
+
+		if valid := ValidatePatternWithSeparator(tt.pattern, tt.separator); valid != (tt.expectedErr == nil) {
+			t.Errorf("#%v. ValidatePatternWithSeparator(%#q, %q) = %v want %v", idx, tt.pattern, tt.separator, valid, tt.expectedErr == nil)
+		}
+	}
+}
+
+// testNormalizer implements Normalizer for a handful of characters so we
+// don't need to depend on golang.org/x/text in tests.
+type testNormalizer string
+
+var testNFC = testNormalizer("NFC")
+var testNFD = testNormalizer("NFD")
+
+var testCompositions = []string{"é", "e\u0301", "ñ", "n\u0303", "É", "E\u0301"}
+
+func (n testNormalizer) String(s string) string {
+	for i := 0; i < len(testCompositions); i += 2 {
+		if n == testNFC {
+			s = strings.ReplaceAll(s, testCompositions[i+1], testCompositions[i])
+		} else {
+			s = strings.ReplaceAll(s, testCompositions[i], testCompositions[i+1])
+		}
+	}
+	return s
+}
+
+func TestMatchDir(t *testing.T) {
+	tests := []struct {
+		pattern, name string
+		isDir         bool
+		opts          []MatchOption
+		expected      bool
+		err           error
+	}{
+		{"build/", "build", true, nil, true, nil},
+		{"build/", "build", false, nil, false, nil},
+		{"build/", "build/", false, nil, true, nil},
+		{"build", "build", false, nil, true, nil},
+		{"build", "build", true, nil, true, nil},
+		{"build", "build/", false, nil, true, nil},
+		{"*/", "a", true, nil, true, nil},
+		{"*/", "a", false, nil, false, nil},
+		{"**/", "a/b/c", true, nil, true, nil},
+		{"**/", "a/b/c", false, nil, false, nil},
+		{"a/**/", "a", true, nil, true, nil},
+		{"**/build/", "x/y/build", true, nil, true, nil},
+		{"**/build/", "x/y/build", false, nil, false, nil},
+		{`a\/`, "a/", false, nil, true, nil},
+		{`a\/`, "a", true, nil, true, nil},
+		{`a\/`, "a", false, nil, false, nil},
+		{`a\\/`, `a\`, true, nil, true, nil},
+		{"/", "/", true, nil, true, nil},
+		{"*", "a", true, []MatchOption{WithFilesOnly()}, false, nil},
+		{"*", "a", false, []MatchOption{WithFilesOnly()}, true, nil},
+		{"*", "a", false, []MatchOption{WithDirsOnly()}, false, nil},
+		{"*", "a", true, []MatchOption{WithDirsOnly()}, true, nil},
+		{"*", "a/", false, []MatchOption{WithDirsOnly()}, true, nil},
+		{"*", "a", true, []MatchOption{WithFilesOnly(), WithDirsOnly()}, false, nil},
+		{"*/", "a", false, []MatchOption{WithFilesOnly()}, false, nil},
+		{"a.*.", "a.b", true, []MatchOption{WithSeparator('.')}, true, nil},
+		{"a.*.", "a.b", false, []MatchOption{WithSeparator('.')}, false, nil},
+		{`a\*\`, `a\b`, true, []MatchOption{WithSeparator('\\')}, true, nil},
+		{"A/", "a", true, []MatchOption{WithCaseInsensitive()}, true, nil},
+		{"[/", "[", true, nil, false, ErrBadPattern},
+	}
+
+	for idx, tt := range tests {
+		matched, err := MatchDir(tt.pattern, tt.name, tt.isDir, tt.opts...)
+		if matched != tt.expected || err != tt.err {
+			t.Errorf("#%v. MatchDir(%#q, %#q, %v, %#v) = %v, %v want %v, %v", idx, tt.pattern, tt.name, tt.isDir, newGlob(tt.opts...), matched, err, tt.expected, tt.err)
+		}
+	}
+}
+
+func TestGlobDirPatterns(t *testing.T) {
+	fsys := fstest.MapFS{
+		"build/out.js":   {},
+		"build/sub/x.js": {},
+		"src/build":      {},
+		"src/a.go":       {},
+		"src/lib/b.go":   {},
+		"x":              {},
+	}
+
+	var all []string
+	isDir := make(map[string]bool)
+	fs.WalkDir(fsys, ".", func(p string, d fs.DirEntry, err error) error {
+		// Glob only returns `.` for `**`, which MatchDir can't know about, since
+		// `.` is never returned by fs.ReadDir
+		if p != "." {
+			all = append(all, p)
+		}
+		isDir[p] = d.IsDir()
+		return nil
+	})
+
+	patterns := []string{
+		"build/", "build", "x/", "x", "*/", "*", "*/*/", "**/build/", "**/build",
+		"**/", "**", "src/**/", "{build,x}/", "{build,x}",
+	}
+	optSets := [][]GlobOption{nil, {WithFilesOnly()}, {WithDirsOnly()}}
+
+	for idx, pattern := range patterns {
+		for _, opts := range optSets {
+			var expected []string
+			for _, p := range all {
+				if matched, _ := MatchDir(pattern, p, isDir[p], opts...); matched {
+					expected = append(expected, p)
+				}
+			}
+
+			matches, err := Glob(fsys, pattern, opts...)
+			if err != nil || !compareSlices(trimSlashes(matches), expected) {
+				t.Errorf("#%v. Glob(%#q, %#v) = %#v, %v want %#v (from MatchDir)", idx, pattern, newGlob(opts...), matches, err, expected)
+			}
+
+			matches = nil
+			err = GlobWalk(fsys, pattern, func(p string, d fs.DirEntry) error {
+				matches = append(matches, p)
+				return nil
+			}, opts...)
+			if err != nil || !compareSlices(trimSlashes(matches), expected) {
+				t.Errorf("#%v. GlobWalk(%#q, %#v) = %#v, %v want %#v (from MatchDir)", idx, pattern, newGlob(opts...), matches, err, expected)
+			}
+		}
+	}
+}
+
+// Glob returns patterns without meta characters as written, including any
+// trailing slash. `.` is removed: see TestGlobDirPatterns.
+func trimSlashes(paths []string) []string {
+	var trimmed []string
+	for _, p := range paths {
+		if p != "." {
+			trimmed = append(trimmed, strings.TrimSuffix(p, "/"))
+		}
+	}
+	return trimmed
+}
+
+func TestMatchWithUnicodeNormalization(t *testing.T) {
+	nfc := "caf\u00e9/*.txt"
+	nfd := "cafe\u0301/a.txt"
+	if ok, _ := Match(nfc, nfd); ok {
+		t.Errorf("Match(%#q, %#q) should not match", nfc, nfd)
+	}
+	for _, form := range []Normalizer{testNFC, testNFD} {
+		if ok, err := MatchWithOptions(nfc, nfd, WithUnicodeNormalization(form)); !ok || err != nil {
+			t.Errorf("MatchWithOptions(%#q, %#q, %v) = %v, %v want true, nil", nfc, nfd, form, ok, err)
+		}
+		if ok, err := MatchWithOptions("cafe\u0301/*", "caf\u00e9/a.txt", WithUnicodeNormalization(form)); !ok || err != nil {
+			t.Errorf("MatchWithOptions(NFD, NFC, %v) = %v, %v want true, nil", form, ok, err)
+		}
+	}
+	if ok, err := MatchWithOptions("caf?", "cafe\u0301", WithUnicodeNormalization(testNFC)); !ok || err != nil {
+		t.Errorf("MatchWithOptions(`caf?`, NFD, NFC) = %v, %v want true, nil", ok, err)
+	}
+	if ok, err := MatchWithOptions("CAFÉ", "cafe\u0301", WithUnicodeNormalization(testNFC), WithCaseInsensitive()); !ok || err != nil {
+		t.Errorf("MatchWithOptions(`CAFÉ`, NFD, NFC, WithCaseInsensitive) = %v, %v want true, nil", ok, err)
+	}
+}
+
+func TestMatchWithCaseFolding(t *testing.T) {
+	tests := []struct {
+		pattern, testPath string
+		shouldMatch       bool
+	}{
+		{"abc", "ABC", true},
+		{"ſ", "s", true},
+		{"S", "ſ", true},
+		{"ΟΔΟΣ", "οδος", true},
+		{"ΟΔΟΣ", "οδοσ", true},
+		{"οδος", "ΟΔΟΣ", true},
+		{"\u212a", "k", true},
+		{"[a-z]", "Q", true},
+		{"[A-Z]", "q", true},
+		{"[^a-z]", "Q", false},
+		{"[s]", "ſ", true},
+		{"a", "b", false},
+	}
+
+	for idx, tt := range tests {
+		ok, err := MatchWithOptions(tt.pattern, tt.testPath, WithCaseInsensitive())
+		if ok != tt.shouldMatch || err != nil {
+			t.Errorf("#%v. MatchWithOptions(%#q, %#q, WithCaseInsensitive) = %v, %v want %v, nil", idx, tt.pattern, tt.testPath, ok, err, tt.shouldMatch)
+		}
+	}
+}
+
+func TestGlobWithUnicodeNormalization(t *testing.T) {
+	fsys := fstest.MapFS{
+		"cafe\u0301/a.txt":     {},
+		"cafe\u0301/b.txt":     {},
+		"cafe\u0301/n\u0303":   {},
+		"x/cafe\u0301/c.txt":   {},
+		"x/cafe\u0301/e\u0301": {},
+	}
+
+	tests := []struct {
+		pattern  string
+		expected []string
+	}{
+		{"caf\u00e9/*.txt", []string{"cafe\u0301/a.txt", "cafe\u0301/b.txt"}},
+		{"caf\u00e9/\u00f1", []string{"cafe\u0301/n\u0303"}},
+		{"caf\u00e9/", []string{"cafe\u0301/"}},
+		{"*/caf\u00e9/*", []string{"x/cafe\u0301/c.txt", "x/cafe\u0301/e\u0301"}},
+		{"**/caf\u00e9/*.txt", []string{"cafe\u0301/a.txt", "cafe\u0301/b.txt", "x/cafe\u0301/c.txt"}},
+		{"x/caf\u00e9/{c.txt,\u00e9}", []string{"x/cafe\u0301/c.txt", "x/cafe\u0301/e\u0301"}},
+		{"x/*/[\u00e9]", []string{"x/cafe\u0301/e\u0301"}},
+	}
+
+	for idx, tt := range tests {
+		matches, err := Glob(fsys, tt.pattern, WithUnicodeNormalization(testNFC))
+		if err != nil || !compareSlices(matches, tt.expected) {
+			t.Errorf("#%v. Glob(%#q, WithUnicodeNormalization) = %#v, %v want %#v, nil", idx, tt.pattern, matches, err, tt.expected)
+		}
+
+		matches = nil
+		err = GlobWalk(fsys, tt.pattern, func(p string, d fs.DirEntry) error {
+			matches = append(matches, p)
+			return nil
+		}, WithUnicodeNormalization(testNFC))
+		if err != nil || !compareSlices(matches, tt.expected) {
+			t.Errorf("#%v. GlobWalk(%#q, WithUnicodeNormalization) = %#v, %v want %#v, nil", idx, tt.pattern, matches, err, tt.expected)
+		}
+	}
+
+	dir := t.TempDir()
+	mkdirp(dir, "cafe\u0301")
+	touch(dir, "cafe\u0301", "a.txt")
+	pattern := filepath.Join(dir, "caf\u00e9", "*.txt")
+	matches, err := FilepathGlob(pattern, WithUnicodeNormalization(testNFC))
+	expected := []string{filepath.Join(dir, "cafe\u0301", "a.txt")}
+	if err != nil || !compareSlices(matches, expected) {
+		t.Errorf("FilepathGlob(%#q, WithUnicodeNormalization) = %#v, %v want %#v, nil", pattern, matches, err, expected)
+	}
+}
+
+func TestMatchWithByteSemantics(t *testing.T) {
+	tests := []struct {
+		pattern, testPath string
+		shouldMatch       bool
+		runeShouldMatch   bool
+	}{
+		{`a?b`, "a\xffb", true, true},
+		{`a?b`, "a☺b", false, true},
+		{`a???b`, "a☺b", true, false},
+		{`a\xffb`, "a\xffb", true, false},
+		{`a\xFFb`, "a\xffb", true, false},
+		{`a\x2a`, "a*", true, false},
+		{`a\x2a`, "ab", false, false},
+		{`a\xg0`, "axg0", true, true},
+		{"a[\xfe]b", "a\xffb", false, true},
+		{`a[\xfe\xff]b`, "a\xffb", true, false},
+		{`a[\x80-\xff]b`, "a\xc3b", true, false},
+		{`a[\x80-\xff]b`, "a\x7fb", false, false},
+		{`a[^\x80-\xff]b`, "a\x7fb", true, true},
+		{`a\xfe`, "a\xff", false, false},
+		{`a\xfe*`, "a\xfe\xff/", false, false},
+		{`*/\xff*`, "a/\xff.txt", true, false},
+		{`a\x2f*`, "a/b", true, false},
+		{`*\xa9`, "\xc3\xa9", true, false},
+		{`**\xa9`, "\xc3\xa9", true, false},
+		{`**/*\xa9`, "a/b/\xc3\xa9", true, false},
+		{`**/\xa9`, "\xc3\xa9/\xa9", true, false},
+	}
+
+	for idx, tt := range tests {
+		ok, err := MatchWithOptions(tt.pattern, tt.testPath, WithByteSemantics())
+		if ok != tt.shouldMatch || err != nil {
+			t.Errorf("#%v. MatchWithOptions(%#q, %#q, WithByteSemantics) = %v, %v want %v, nil", idx, tt.pattern, tt.testPath, ok, err, tt.shouldMatch)
+		}
+
+		ok, _ = Match(tt.pattern, tt.testPath)
+		if ok != tt.runeShouldMatch {
+			t.Errorf("#%v. Match(%#q, %#q) = %v want %v", idx, tt.pattern, tt.testPath, ok, tt.runeShouldMatch)
+		}
+	}
+
+	if ok, _ := MatchWithOptions(`A[A-Z]\xc3`, "ab\xc3", WithByteSemantics(), WithCaseInsensitive()); !ok {
+		t.Errorf("MatchWithOptions(`A[A-Z]\\xc3`, WithByteSemantics, WithCaseInsensitive) should match")
+	}
+	if ok, _ := MatchWithOptions(`\xe9`, "\xc9", WithByteSemantics(), WithCaseInsensitive()); ok {
+		t.Errorf("MatchWithOptions(`\\xe9`, WithByteSemantics, WithCaseInsensitive) should not fold non-ASCII bytes")
+	}
+}
+
+func TestGlobWithByteSemantics(t *testing.T) {
+	// io/fs requires that paths be valid UTF-8, so only the last path segment
+	// may contain invalid sequences
+	fsys := fstest.MapFS{
+		"a\xff":       {},
+		"a\xfe.txt":   {},
+		"ab/\xfe.txt": {},
+		"ab/b.txt":    {},
+		"a☺/c.txt":    {},
+	}
+
+	tests := []struct {
+		pattern  string
+		expected []string
+	}{
+		{`a?`, []string{"a\xff", "ab"}},
+		{`a???/*`, []string{"a☺/c.txt"}},
+		{`a[\x80-\xff]*`, []string{"a\xff", "a\xfe.txt", "a☺"}},
+		{`a[\xfe-\xff]*`, []string{"a\xff", "a\xfe.txt"}},
+		{`ab/[\x80-\xff]*`, []string{"ab/\xfe.txt"}},
+		{`**/\xfe.txt`, []string{"ab/\xfe.txt"}},
+		{`**/?.txt`, []string{"ab/\xfe.txt", "ab/b.txt", "a☺/c.txt"}},
+	}
+
+	for idx, tt := range tests {
+		matches, err := Glob(fsys, tt.pattern, WithByteSemantics())
+		if err != nil || !compareSlices(matches, tt.expected) {
+			t.Errorf("#%v. Glob(%#q, WithByteSemantics) = %#v, %v want %#v, nil", idx, tt.pattern, matches, err, tt.expected)
+		}
+	}
+}
+
+func TestGlobWithStrictDoubleStar(t *testing.T) {
+	fsys := fstest.MapFS{"a/b.txt": {}}
+	if _, err := Glob(fsys, "a/**.txt", WithStrictDoubleStar()); err != ErrBadPattern {
+		t.Errorf("Glob(`a/**.txt`, WithStrictDoubleStar) error = %v want %v", err, ErrBadPattern)
+	}
+	if err := GlobWalk(fsys, "a**/*", func(p string, d fs.DirEntry) error { return nil }, WithStrictDoubleStar()); err != ErrBadPattern {
+		t.Errorf("GlobWalk(`a**/*`, WithStrictDoubleStar) error = %v want %v", err, ErrBadPattern)
+	}
+	if matches, err := Glob(fsys, "a/**/*.txt", WithStrictDoubleStar()); err != nil || !compareSlices(matches, []string{"a/b.txt"}) {
+		t.Errorf("Glob(`a/**/*.txt`, WithStrictDoubleStar) = %#v, %v want [a/b.txt], nil", matches, err)
+	}
+}
+
+func TestGlobWithSkipHidden(t *testing.T) {
+	fsys := fstest.MapFS{
+		".a.yaml":             {},
+		"a.yaml":              {},
+		".github/ci.yaml":     {},
+		".github/x/y.yaml":    {},
+		"b/.c/d.yaml":         {},
+		"b/c/.d.yaml":         {},
+		"b/c/d.yaml":          {},
+		"b/c/.git/HEAD":       {},
+		"b/c/.git/refs/x.txt": {},
+	}
+
+	tests := []struct {
+		pattern  string
+		expected []string
+	}{
+		{"*.yaml", []string{"a.yaml"}},
+		{".*.yaml", []string{".a.yaml"}},
+		{"**/*.yaml", []string{"a.yaml", "b/c/d.yaml"}},
+		{"**/.*.yaml", []string{".a.yaml", "b/c/.d.yaml"}},
+		{".github/**", []string{".github", ".github/ci.yaml", ".github/x", ".github/x/y.yaml"}},
+		{"b/**", []string{"b", "b/c", "b/c/d.yaml"}},
+		{"b/*/.git/*", []string{"b/c/.git/HEAD", "b/c/.git/refs"}},
+		{"{.github,b}/*", []string{".github/ci.yaml", ".github/x", "b/c"}},
+	}
+
+	for idx, tt := range tests {
+		matches, err := Glob(fsys, tt.pattern, WithSkipHidden())
+		if err != nil || !compareSlices(matches, tt.expected) {
+			t.Errorf("#%v. Glob(%#q, WithSkipHidden) = %#v, %v want %#v, nil", idx, tt.pattern, matches, err, tt.expected)
+		}
+
+		matches = nil
+		err = GlobWalk(fsys, tt.pattern, func(p string, d fs.DirEntry) error {
+			matches = append(matches, p)
+			return nil
+		}, WithSkipHidden())
+		if err != nil || !compareSlices(matches, tt.expected) {
+			t.Errorf("#%v. GlobWalk(%#q, WithSkipHidden) = %#v, %v want %#v, nil", idx, tt.pattern, matches, err, tt.expected)
+		}
+
+		for _, m := range tt.expected {
+			if ok, _ := MatchWithOptions(tt.pattern, m, WithSkipHidden()); !ok {
+				t.Errorf("#%v. MatchWithOptions(%#q, %#q, WithSkipHidden) should match", idx, tt.pattern, m)
+			}
+		}
+	}
+}
+
 func BenchmarkGlob(b *testing.B) {
 	fsys := os.DirFS("test")
 	b.ReportAllocs()
@@ -4,6 +4,8 @@ import (
 	"errors"
 	"io/fs"
 	"path"
+	"strings"
+	"time"
 )
 
 // Glob returns the names of all files matching pattern or nil if there is no
@@ -30,25 +32,153 @@ import (
 // Note: users should _not_ count on the returned error,
 // doublestar.ErrBadPattern, being equal to path.ErrBadPattern.
 func Glob(fsys fs.FS, pattern string, opts ...GlobOption) ([]string, error) {
-	if !ValidatePattern(pattern) {
+	g := newGlob(opts...)
+	if g.stats != nil {
+		defer addSince(&g.stats.TotalTime, time.Now())
+	}
+	if !g.validatePattern(pattern, '/') {
 		return nil, ErrBadPattern
 	}
+	pattern = g.normalize(pattern)
 
-	g := newGlob(opts...)
+	if g.sorted {
+		entries, err := g.doGlobSorted(fsys, pattern)
+		entries = g.truncateMatches(entries)
+		var matches []string
+		for _, m := range entries {
+			matches = append(matches, m.Path)
+		}
+		return matches, g.collectedErrors(err)
+	}
 
-	if hasMidDoubleStar(pattern) {
+	if hasMidDoubleStar(pattern) || g.breadthFirst || g.limit > 0 {
 		// If the pattern has a `**` anywhere but the very end, GlobWalk is more
 		// performant because it can get away with less allocations. If the pattern
 		// ends in a `**`, both methods are pretty much the same, but Glob has a
 		// _very_ slight advantage because of lower function call overhead.
+		// Breadth-first traversal and limits are only implemented by GlobWalk.
 		var matches []string
-		err := g.doGlobWalk(fsys, pattern, true, true, func(p string, d fs.DirEntry) error {
+		err := g.doGlobWalk(fsys, pattern, true, true, g.limitWalkFunc(func(p string, d fs.DirEntry) error {
 			matches = append(matches, p)
 			return nil
-		})
-		return matches, err
+		}))
+		return matches, g.collectedErrors(ignoreLimitReached(err))
+	}
+	matches, err := g.doGlob(fsys, pattern, nil, true, true)
+	return matches, g.collectedErrors(err)
+}
+
+// GlobMatch is a single match returned by GlobEntries. It carries the path of
+// the match, using `/` as the path separator, and the fs.DirEntry that was
+// read from the file system while globbing.
+type GlobMatch struct {
+	Path  string
+	Entry fs.DirEntry
+
+	info     fs.FileInfo
+	infoErr  error
+	infoDone bool
+}
+
+// Info returns the fs.FileInfo for the match. It is retrieved from Entry the
+// first time Info is called, and cached for subsequent calls. Like
+// fs.DirEntry's Info method, if the match is a symlink, the returned FileInfo
+// describes the symlink itself, not its target, unless the symlink was part of
+// the pattern before any meta characters.
+func (m *GlobMatch) Info() (fs.FileInfo, error) {
+	if !m.infoDone {
+		m.info, m.infoErr = m.Entry.Info()
+		m.infoDone = true
+	}
+	return m.info, m.infoErr
+}
+
+// GlobEntries is like Glob, but returns a *GlobMatch for each match, which
+// includes the fs.DirEntry that was read while globbing. This allows callers
+// to check file types, sizes, modes, etc, without needing to run fs.Stat() on
+// each match.
+//
+// The same options that can be passed to Glob can be passed to GlobEntries,
+// and the same limitations apply.
+func GlobEntries(fsys fs.FS, pattern string, opts ...GlobOption) ([]*GlobMatch, error) {
+	g := newGlob(opts...)
+	if g.stats != nil {
+		defer addSince(&g.stats.TotalTime, time.Now())
+	}
+	if !g.validatePattern(pattern, '/') {
+		return nil, ErrBadPattern
+	}
+	pattern = g.normalize(pattern)
+
+	if g.sorted {
+		entries, err := g.doGlobSorted(fsys, pattern)
+		entries = g.truncateMatches(entries)
+		var matches []*GlobMatch
+		for _, m := range entries {
+			matches = append(matches, &GlobMatch{Path: m.Path, Entry: m.Entry})
+		}
+		return matches, g.collectedErrors(err)
+	}
+
+	var matches []*GlobMatch
+	err := g.doGlobWalk(fsys, pattern, true, true, g.limitWalkFunc(func(p string, d fs.DirEntry) error {
+		matches = append(matches, &GlobMatch{Path: p, Entry: d})
+		return nil
+	}))
+	return matches, g.collectedErrors(ignoreLimitReached(err))
+}
+
+// GlobFirst returns the first file matching pattern. The boolean result is
+// false if nothing matches. The syntax of pattern and the behavior are the same
+// as Glob(), except that globbing stops as soon as a match is found. Unless
+// WithSortOrder is passed, which match is "first" depends on the order in
+// which the fs.FS returns directory entries.
+func GlobFirst(fsys fs.FS, pattern string, opts ...GlobOption) (string, bool, error) {
+	var match string
+	found := false
+	err := GlobWalk(fsys, pattern, func(p string, d fs.DirEntry) error {
+		match = p
+		found = true
+		return nil
+	}, append(opts[:len(opts):len(opts)], WithLimit(1))...)
+	if err != nil {
+		return "", false, err
+	}
+	return match, found, nil
+}
+
+// GlobExists returns true if any file matches pattern. The syntax of pattern
+// and the behavior are the same as Glob(), except that globbing stops as soon
+// as a match is found.
+func GlobExists(fsys fs.FS, pattern string, opts ...GlobOption) (bool, error) {
+	_, found, err := GlobFirst(fsys, pattern, opts...)
+	return found, err
+}
+
+// Finds all matches and sorts them according to WithSortOrder
+func (g *glob) doGlobSorted(fsys fs.FS, pattern string) ([]DirEntryWithFullPath, error) {
+	var matches []DirEntryWithFullPath
+	err := g.doGlobWalk(fsys, pattern, true, true, func(p string, d fs.DirEntry) error {
+		matches = append(matches, DirEntryWithFullPath{d, p})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	start := time.Now()
+	g.sortMatches(fsys, matches)
+	if g.stats != nil {
+		g.stats.SortTime += time.Since(start)
+	}
+	return matches, nil
+}
+
+// Returns at most as many matches as allowed by WithLimit
+func (g *glob) truncateMatches(matches []DirEntryWithFullPath) []DirEntryWithFullPath {
+	if g.limit > 0 && len(matches) > g.limit {
+		return matches[:g.limit]
 	}
-	return g.doGlob(fsys, pattern, nil, true, true)
+	return matches
 }
 
 // Does the actual globbin'
@@ -66,13 +196,13 @@ func (g *glob) doGlob(fsys fs.FS, pattern string, m []string, firstSegment, befo
 		// pattern doesn't contain any meta characters - does a file matching the
 		// pattern exist?
 		// The pattern may contain escaped wildcard characters for an exact path match.
-		path := unescapeMeta(pattern)
+		path := g.resolveNormalized(fsys, g.unescapeMeta(pattern))
 		pathInfo, pathExists, pathErr := g.exists(fsys, path, beforeMeta)
 		if pathErr != nil {
 			return nil, pathErr
 		}
 
-		if pathExists && (!firstSegment || !g.filesOnly || !pathInfo.IsDir()) {
+		if pathExists && (pathInfo.IsDir() || !isDirPattern(path)) && (!firstSegment || g.matchesFileType(pathInfo.IsDir())) {
 			matches = append(matches, path)
 		}
 
@@ -106,7 +236,7 @@ func (g *glob) doGlob(fsys fs.FS, pattern string, m []string, firstSegment, befo
 	// characters. They would be equal if they are both -1, which means `dir`
 	// will be ".", and we know that doesn't have meta characters either.
 	if splitIdx <= patternStart {
-		return g.globDir(fsys, unescapeMeta(dir), pattern, matches, firstSegment, beforeMeta)
+		return g.globDir(fsys, g.resolveNormalized(fsys, g.unescapeMeta(dir)), pattern, matches, firstSegment, beforeMeta)
 	}
 
 	var dirs []string
@@ -158,6 +288,7 @@ func (g *glob) globAlts(fsys fs.FS, pattern string, openingIdx, closingIdx int,
 				nextIdx += patIdx
 			}
 
+			g.countAltExpanded()
 			alt := buildAlt(d, pattern, startIdx, openingIdx, patIdx, nextIdx, afterIdx)
 			matches, err = g.doGlob(fsys, alt, matches, firstSegment, beforeMeta)
 			if err != nil {
@@ -212,34 +343,37 @@ func (g *glob) globDir(fsys fs.FS, dir, pattern string, matches []string, canMat
 		return g.globDoubleStar(fsys, dir, m, canMatchFiles, beforeMeta)
 	}
 
-	dirs, err := fs.ReadDir(fsys, dir)
+	dirs, err := g.readDir(fsys, dir)
 	if err != nil {
 		if errors.Is(err, fs.ErrNotExist) {
 			e = g.handlePatternNotExist(beforeMeta)
-		} else {
-			e = g.forwardErrIfFailOnIOErrors(err)
+			return
+		}
+		var skip bool
+		if skip, e = g.handleIOError("readdir", dir, err); skip {
+			return
 		}
-		return
 	}
 
 	var matched bool
 	for _, info := range dirs {
 		name := info.Name()
-		matched, e = matchWithSeparator(pattern, name, '/', false, g.caseInsensitive)
+		matched, e = g.matchEntry(pattern, dir, name)
 		if e != nil {
 			return
 		}
 		if matched {
 			matched = canMatchFiles
-			if !matched || g.filesOnly {
-				matched, e = g.isDir(fsys, dir, name, info)
+			if !matched || g.filesOnly || g.dirsOnly {
+				var isDir bool
+				isDir, e = g.isDir(fsys, dir, name, info)
 				if e != nil {
 					return
 				}
+				matched = isDir
 				if canMatchFiles {
-					// if we're here, it's because g.filesOnly
-					// is set and we don't want directories
-					matched = !matched
+					// if we're here, it's because g.filesOnly or g.dirsOnly is set
+					matched = g.matchesFileType(isDir)
 				}
 			}
 			if matched {
@@ -252,12 +386,13 @@ func (g *glob) globDir(fsys fs.FS, dir, pattern string, matches []string, canMat
 }
 
 func (g *glob) globDoubleStar(fsys fs.FS, dir string, matches []string, canMatchFiles, beforeMeta bool) ([]string, error) {
-	dirs, err := fs.ReadDir(fsys, dir)
+	dirs, err := g.readDir(fsys, dir)
 	if err != nil {
 		if errors.Is(err, fs.ErrNotExist) {
 			return matches, g.handlePatternNotExist(beforeMeta)
-		} else {
-			return matches, g.forwardErrIfFailOnIOErrors(err)
+		}
+		if skip, err := g.handleIOError("readdir", dir, err); skip {
+			return matches, err
 		}
 	}
 
@@ -268,6 +403,13 @@ func (g *glob) globDoubleStar(fsys fs.FS, dir string, matches []string, canMatch
 
 	for _, info := range dirs {
 		name := info.Name()
+		if g.isHidden(name) {
+			// `**` cannot match hidden files or directories
+			if info.IsDir() {
+				g.countDirPruned()
+			}
+			continue
+		}
 		isDir, err := g.isDir(fsys, dir, name, info)
 		if err != nil {
 			return nil, err
@@ -277,7 +419,7 @@ func (g *glob) globDoubleStar(fsys fs.FS, dir string, matches []string, canMatch
 			if err != nil {
 				return nil, err
 			}
-		} else if canMatchFiles {
+		} else if canMatchFiles && !g.dirsOnly {
 			matches = append(matches, path.Join(dir, name))
 		}
 	}
@@ -305,6 +447,12 @@ func hasMidDoubleStar(p string) bool {
 	return false
 }
 
+// Returns true if `p`, an unescaped path, ends in a slash, meaning that it
+// should only match a directory.
+func isDirPattern(p string) bool {
+	return len(p) > 1 && p[len(p)-1] == '/'
+}
+
 // Returns the index of the first unescaped meta character, or negative 1.
 func indexMeta(s string) int {
 	var c byte
@@ -367,21 +515,29 @@ func (g *glob) exists(fsys fs.FS, name string, beforeMeta bool) (fs.FileInfo, bo
 		name = name[:namelen-1]
 	}
 
-	info, err := fs.Stat(fsys, name)
-	if errors.Is(err, fs.ErrNotExist) {
-		return nil, false, g.handlePatternNotExist(beforeMeta)
+	info, err := g.stat(fsys, name)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, false, g.handlePatternNotExist(beforeMeta)
+		}
+		_, err = g.handleIOError("stat", name, err)
+		return nil, false, err
 	}
-	return info, err == nil, g.forwardErrIfFailOnIOErrors(err)
+	return info, true, nil
 }
 
 // Returns true if the path exists and is a directory or a symlink to a
 // directory
 func (g *glob) isPathDir(fsys fs.FS, name string, beforeMeta bool) (fs.FileInfo, bool, error) {
-	info, err := fs.Stat(fsys, name)
-	if errors.Is(err, fs.ErrNotExist) {
-		return nil, false, g.handlePatternNotExist(beforeMeta)
+	info, err := g.stat(fsys, name)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, false, g.handlePatternNotExist(beforeMeta)
+		}
+		_, err = g.handleIOError("stat", name, err)
+		return nil, false, err
 	}
-	return info, err == nil && info.IsDir(), g.forwardErrIfFailOnIOErrors(err)
+	return info, info.IsDir(), nil
 }
 
 // Returns whether or not the given DirEntry is a directory. If the DirEntry
@@ -393,20 +549,71 @@ func (g *glob) isDir(fsys fs.FS, dir, name string, info fs.DirEntry) (bool, erro
 		if dir != "" {
 			p = path.Join(dir, name)
 		}
-		finfo, err := fs.Stat(fsys, p)
+		finfo, err := g.stat(fsys, p)
 		if err != nil {
 			if errors.Is(err, fs.ErrNotExist) {
 				// this function is only ever called while expanding a glob, so it can
 				// never return ErrPatternNotExist
 				return false, nil
 			}
-			return false, g.forwardErrIfFailOnIOErrors(err)
+			_, err = g.handleIOError("stat", p, err)
+			return false, err
 		}
+		g.countSymlinkFollowed()
 		return finfo.IsDir(), nil
 	}
 	return info.IsDir(), nil
 }
 
+// If WithUnicodeNormalization is enabled and `p` does not exist, attempts to
+// find a path that is equal to `p` under normalization by reading each parent
+// directory. If such a path is found, it is returned. Otherwise, `p` is
+// returned unaltered: any errors will be reported when `p` is accessed later.
+func (g *glob) resolveNormalized(fsys fs.FS, p string) string {
+	if g.normalizer == nil || p == "." || p == "" {
+		return p
+	}
+
+	// p might end in a slash, but Stat doesn't like that
+	trailingSlash := ""
+	if len(p) > 1 && p[len(p)-1] == '/' {
+		p = p[:len(p)-1]
+		trailingSlash = "/"
+	}
+
+	if _, err := g.stat(fsys, p); err == nil || !errors.Is(err, fs.ErrNotExist) {
+		return p + trailingSlash
+	}
+
+	resolved := "."
+	for _, segment := range strings.Split(p, "/") {
+		entries, err := g.readDir(fsys, resolved)
+		if err != nil {
+			return p + trailingSlash
+		}
+
+		normalized := g.normalize(segment)
+		found := false
+		for _, entry := range entries {
+			if g.normalize(entry.Name()) == normalized {
+				resolved = path.Join(resolved, entry.Name())
+				found = true
+				break
+			}
+		}
+		if !found {
+			return p + trailingSlash
+		}
+	}
+	return resolved + trailingSlash
+}
+
+// Returns true if WithSkipHidden is enabled and name is hidden, ie, starts
+// with a dot.
+func (g *glob) isHidden(name string) bool {
+	return g.skipHidden && len(name) > 0 && name[0] == '.'
+}
+
 // Builds a string from an alt
 func buildAlt(prefix, pattern string, startIdx, openingIdx, currentIdx, nextIdx, afterIdx int) string {
 	// pattern:
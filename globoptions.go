@@ -1,6 +1,11 @@
 package doublestar
 
-import "strings"
+import (
+	"fmt"
+	"io/fs"
+	"strconv"
+	"strings"
+)
 
 // glob is an internal type to store options during globbing.
 type glob struct {
@@ -8,13 +13,42 @@ type glob struct {
 	failOnIOErrors        bool
 	failOnPatternNotExist bool
 	filesOnly             bool
+	dirsOnly              bool
 	noFollow              bool
+	separator             rune
+	skipHidden            bool
+	strictDoubleStar      bool
+	normalizer            Normalizer
+	byteSemantics         bool
+	sorted                bool
+	sortOrder             SortOrder
+	breadthFirst          bool
+	limit                 int
+	errorHandler          func(path string, err error) error
+	collectErrors         bool
+	stats                 *Stats
+	trace                 func(Event)
+
+	// I/O errors collected while globbing if collectErrors is enabled
+	errs      []error
+	errsPaths map[string]bool
+
+	// callbacks set by GlobWalkDir, and the state needed to call them
+	enter       func(dir string) error
+	leave       func(dir string)
+	activeDirs  map[string]int
+	skippedDirs map[string]bool
 }
 
 // GlobOption represents a setting that can be passed to Glob, GlobWalk, and
 // FilepathGlob.
 type GlobOption func(*glob)
 
+// MatchOption represents a setting that can be passed to MatchWithOptions.
+// MatchOption is an alias of GlobOption so that the same options can be passed
+// to MatchWithOptions and Glob, producing identical results.
+type MatchOption = GlobOption
+
 // Construct a new glob object with the given options
 func newGlob(opts ...GlobOption) *glob {
 	g := &glob{}
@@ -28,6 +62,8 @@ func newGlob(opts ...GlobOption) *glob {
 // FilepathGlob. If passed, doublestar will treat all alphabetic characters as
 // case insensitive (i.e. "a" in the pattern would match "a" or "A"). This is
 // useful for platforms like Windows where paths are case insensitive by default.
+// Characters are compared using Unicode simple case folding, so, for example,
+// "ς", "σ", and "Σ" are all considered equal.
 func WithCaseInsensitive() GlobOption {
 	return func(g *glob) {
 		g.caseInsensitive = true
@@ -58,9 +94,9 @@ func WithFailOnPatternNotExist() GlobOption {
 	}
 }
 
-// WithFilesOnly is an option that can be passed to Glob, GlobWalk, or
-// FilepathGlob. If passed, doublestar will only return files that match the
-// pattern, not directories.
+// WithFilesOnly is an option that can be passed to Glob, GlobWalk,
+// FilepathGlob, or MatchDir. If passed, doublestar will only return files that
+// match the pattern, not directories.
 //
 // Note: if combined with the WithNoFollow option, symlinks to directories
 // _will_ be included in the result since no attempt is made to follow the
@@ -71,6 +107,22 @@ func WithFilesOnly() GlobOption {
 	}
 }
 
+// WithDirsOnly is an option that can be passed to Glob, GlobWalk,
+// FilepathGlob, or MatchDir. If passed, doublestar will only return
+// directories that match the pattern, not files. It is the counterpart of
+// WithFilesOnly: if both are passed, nothing will match. Unlike a pattern
+// ending in a slash, such as `*/`, this option applies to any pattern,
+// including `**`.
+//
+// Note: if combined with the WithNoFollow option, symlinks to directories
+// will _not_ be included in the result since no attempt is made to follow the
+// symlink.
+func WithDirsOnly() GlobOption {
+	return func(g *glob) {
+		g.dirsOnly = true
+	}
+}
+
 // WithNoFollow is an option that can be passed to Glob, GlobWalk, or
 // FilepathGlob. If passed, doublestar will not follow symlinks while
 // traversing the filesystem. However, due to io/fs's _very_ poor support for
@@ -90,14 +142,327 @@ func WithNoFollow() GlobOption {
 	}
 }
 
-// forwardErrIfFailOnIOErrors is used to wrap the return values of I/O
-// functions. When failOnIOErrors is enabled, it will return err; otherwise, it
-// always returns nil.
-func (g *glob) forwardErrIfFailOnIOErrors(err error) error {
+// WithSkipHidden is an option that can be passed to Glob, GlobWalk,
+// FilepathGlob, or MatchWithOptions. If passed, doublestar will follow bash's
+// rules for hidden files and directories (those whose names start with a dot)
+// when the `dotglob` option is disabled: meta characters such as `*`, `?`,
+// `[...]`, and `**` will not match a leading dot. The pattern segment must
+// start with an explicit dot to match a hidden name. For example, `*.yaml`
+// will not match `.config.yaml`, but `.*.yaml` will. Likewise, `**` will not
+// traverse hidden directories, so `**/*.yaml` will not return `.git/a.yaml`.
+func WithSkipHidden() GlobOption {
+	return func(g *glob) {
+		g.skipHidden = true
+	}
+}
+
+// WithSeparator is an option that can be passed to MatchWithOptions or
+// PathMatchWithOptions to change the path separator used to split `pattern`
+// and `name`. Just like PathMatch, if the separator is `\`, escaping will be
+// disabled. Meta characters cannot be used as the separator; see
+// MatchWithSeparator for details.
+//
+// Glob, GlobWalk, and FilepathGlob ignore this option: patterns passed to Glob
+// and GlobWalk always use `/` as the path separator.
+func WithSeparator(separator rune) GlobOption {
+	return func(g *glob) {
+		g.separator = separator
+	}
+}
+
+// WithStrictDoubleStar is an option that can be passed to Glob, GlobWalk,
+// FilepathGlob, MatchWithOptions, or PathMatchWithOptions. By default, a
+// doublestar (`**`) that is not an entire path segment by itself, such as
+// `path/to/**.txt`, behaves like a single star, just like bash's globstar
+// option. If WithStrictDoubleStar is passed, such patterns are instead
+// rejected with ErrBadPattern.
+func WithStrictDoubleStar() GlobOption {
+	return func(g *glob) {
+		g.strictDoubleStar = true
+	}
+}
+
+// Normalizer is implemented by types that can convert a string to a Unicode
+// normalization form, such as the forms defined in
+// golang.org/x/text/unicode/norm (ie, norm.NFC or norm.NFD).
+type Normalizer interface {
+	String(s string) string
+}
+
+// WithUnicodeNormalization is an option that can be passed to Glob, GlobWalk,
+// FilepathGlob, MatchWithOptions, or PathMatchWithOptions. If passed,
+// doublestar will compare the pattern and names after converting both to the
+// given normalization form. For example, macOS commonly creates file names in
+// NFD, whereas patterns typed by a user are usually in NFC. Passing norm.NFC
+// from golang.org/x/text/unicode/norm will allow a pattern such as
+// `café/*.txt` to match regardless of which form was used for either.
+//
+// Paths returned by Glob, GlobWalk, and FilepathGlob are never normalized:
+// they are the names as they exist on the file system. Note that this option
+// may be slower for patterns that reference paths that do not exist exactly
+// as written, since doublestar will need to read each parent directory to find
+// a match.
+//
+// NFC is recommended if your patterns contain character classes with accented
+// characters, since decomposed characters will be treated as multiple
+// characters in the class.
+func WithUnicodeNormalization(form Normalizer) GlobOption {
+	return func(g *glob) {
+		g.normalizer = form
+	}
+}
+
+// WithByteSemantics is an option that can be passed to Glob, GlobWalk,
+// FilepathGlob, MatchWithOptions, or PathMatchWithOptions. By default,
+// doublestar treats patterns and names as UTF-8 encoded strings: for example,
+// `?` matches a single rune, which may be several bytes. However, file names
+// on many systems are arbitrary sequences of bytes that may not be valid
+// UTF-8. If WithByteSemantics is passed, `?` and character classes match a
+// single byte instead, and escapes in the form `\xNN`, where NN are two
+// hexadecimal digits, match the byte NN. For example, `a[\x80-\xff]` will match
+// "a" followed by any byte with the high bit set.
+//
+// If combined with WithCaseInsensitive, only ASCII letters are treated as case
+// insensitive.
+//
+// Note that io/fs requires paths to be valid UTF-8 (see fs.ValidPath), so
+// most fs.FS implementations, including os.DirFS, will refuse to open
+// directories whose names are not. Glob and GlobWalk can still match such
+// names when they appear in the last segment of the pattern.
+func WithByteSemantics() GlobOption {
+	return func(g *glob) {
+		g.byteSemantics = true
+	}
+}
+
+// WithSortOrder is an option that can be passed to Glob, GlobWalk,
+// GlobEntries, or FilepathGlob. By default, matches are returned in the order
+// they are found, which depends on the order in which the fs.FS returns
+// directory entries and how alts (ie, `{...}`) are expanded. If WithSortOrder
+// is passed, matches are guaranteed to be returned in the given order, no
+// matter the fs.FS or pattern: by default, depth-first, with siblings ordered
+// by comparing their names byte-by-byte (SortLexical). See SortOrder for other
+// orders.
+//
+// To guarantee the order, all matches must be found before any are returned.
+// As a result, GlobWalk will not call its callback function until the glob
+// has finished. If the callback function returns SkipDir, descendants of the
+// skipped directory are not passed to the callback, just like without this
+// option.
+func WithSortOrder(order SortOrder) GlobOption {
+	return func(g *glob) {
+		g.sorted = true
+		g.sortOrder = order
+	}
+}
+
+// WithBreadthFirstTraversal is an option that can be passed to Glob,
+// GlobWalk, GlobEntries, or FilepathGlob. By default, `**` walks the
+// filesystem depth-first, descending into each directory as soon as it is
+// found. If WithBreadthFirstTraversal is passed, `**` instead visits every
+// directory at one depth before descending any further, keeping a queue of
+// directories that have yet to be read. This is useful when matches near the
+// root are more interesting than deeper ones: for example, a GlobWalk with
+// `**/go.mod` will find the shallowest go.mod first.
+//
+// Matches are still streamed as they are found, so memory use is bounded by
+// the number of pending directories rather than the number of matches.
+// Returning SkipDir from a GlobWalk callback has the same effect as without
+// this option: a skipped directory is never read, and a skipped file causes
+// the remaining entries of its parent directory to be skipped. Errors are
+// handled the same way, too.
+//
+// Unlike WithSortOrder(SortBreadthFirst), this option does not guarantee any
+// particular order among siblings, nor among matches of different alts.
+func WithBreadthFirstTraversal() GlobOption {
+	return func(g *glob) {
+		g.breadthFirst = true
+	}
+}
+
+// WithLimit is an option that can be passed to Glob, GlobWalk, GlobEntries, or
+// FilepathGlob. If passed, globbing stops as soon as `n` matches have been
+// found: Glob, GlobEntries, and FilepathGlob return at most `n` matches, and
+// GlobWalk calls its callback function at most `n` times. A match counts
+// toward the limit even if the callback returns SkipDir. If `n` is zero or
+// negative, there is no limit.
+//
+// Which matches are returned depends on the order in which they are found,
+// unless WithSortOrder is also passed, in which case the first `n` matches in
+// the requested order are returned.
+func WithLimit(n int) GlobOption {
+	return func(g *glob) {
+		g.limit = n
+	}
+}
+
+// WithErrorHandler is an option that can be passed to Glob, GlobWalk,
+// GlobWalkDir, GlobEntries, or FilepathGlob. By default, I/O errors, such as
+// a directory that cannot be read, are ignored, unless WithFailOnIOErrors is
+// passed, in which case the first such error aborts the glob. If
+// WithErrorHandler is passed, `handler` is called for every I/O error instead,
+// with the path that could not be read, and the error, wrapped in a
+// *fs.PathError for that path. The handler decides what to do next:
+//   - return nil to continue: if the error came from reading a directory, any
+//     entries that were read before the error are still globbed
+//   - return SkipDir to skip the path
+//   - return any other error to stop globbing and return that error
+//
+// If the error came from running fs.Stat, for example, to determine if a
+// symlink points to a directory, returning nil or SkipDir has the same
+// effect: the symlink is not followed. fs.ErrNotExist errors are not passed to
+// the handler: see WithFailOnPatternNotExist. A path may be reported more
+// than once if the pattern requires reading it more than once, such as
+// `**/*.txt`. If WithErrorHandler is passed, WithFailOnIOErrors is ignored.
+func WithErrorHandler(handler func(path string, err error) error) GlobOption {
+	return func(g *glob) {
+		g.errorHandler = handler
+	}
+}
+
+// WithCollectErrors is an option that can be passed to Glob, GlobWalk,
+// GlobWalkDir, GlobEntries, FilepathGlob, or GlobRoot. By default, I/O errors,
+// such as a directory that cannot be read, are ignored, unless
+// WithFailOnIOErrors is passed, in which case the first such error aborts the
+// glob. If WithCollectErrors is passed, globbing continues past I/O errors,
+// skipping paths that cannot be read, and then returns all of the matches
+// that could be found along with an *IOErrors describing every failure. Each
+// path is only reported once, even if the pattern requires reading it more
+// than once.
+//
+// If WithCollectErrors is passed, WithFailOnIOErrors is ignored. If it is
+// combined with WithErrorHandler, errors for which the handler returns nil or
+// SkipDir are collected.
+func WithCollectErrors() GlobOption {
+	return func(g *glob) {
+		g.collectErrors = true
+	}
+}
+
+// Returns `s` in the normalization form set by WithUnicodeNormalization, or
+// `s` unaltered if normalization is not enabled.
+func (g *glob) normalize(s string) string {
+	if g.normalizer == nil {
+		return s
+	}
+	return g.normalizer.String(s)
+}
+
+// Returns the separator set with WithSeparator, or `def` if it was not set.
+func (g *glob) separatorOrDefault(def rune) rune {
+	if g.separator == 0 {
+		return def
+	}
+	return g.separator
+}
+
+// Returns true if a match that is, or is not, a directory should be returned,
+// taking WithFilesOnly and WithDirsOnly into account.
+func (g *glob) matchesFileType(isDir bool) bool {
+	return !(g.filesOnly && isDir) && !(g.dirsOnly && !isDir)
+}
+
+// Validates a pattern, taking WithStrictDoubleStar into account.
+func (g *glob) validatePattern(s string, separator rune) bool {
+	if !doValidatePattern(s, separator) {
+		return false
+	}
+	return !g.strictDoubleStar || validateDoubleStars(s, separator)
+}
+
+// limitWalkFunc wraps `fn` so that it returns errLimitReached once it has been
+// called as many times as allowed by WithLimit. If there is no limit, `fn` is
+// returned unaltered.
+func (g *glob) limitWalkFunc(fn GlobWalkFunc) GlobWalkFunc {
+	if g.limit <= 0 {
+		return fn
+	}
+
+	count := 0
+	return func(p string, d fs.DirEntry) error {
+		err := fn(p, d)
+		if err != nil && err != SkipDir {
+			return err
+		}
+		count++
+		if count >= g.limit {
+			return errLimitReached
+		}
+		return err
+	}
+}
+
+// ignoreLimitReached is used to wrap the return value of a walk that used
+// limitWalkFunc: reaching the limit is not an error.
+func ignoreLimitReached(err error) error {
+	if err == errLimitReached {
+		return nil
+	}
+	return err
+}
+
+// handleIOError handles errors returned by I/O functions, other than
+// fs.ErrNotExist. `err` is wrapped in a *fs.PathError for `name` and passed to
+// the handler set by WithErrorHandler, if any. Returns true if `name` should
+// be skipped; if the handler returned nil, the caller may continue with
+// whatever it was able to read. Returns a non-nil error if globbing should
+// stop: either the handler's error, or, without a handler, `err` if
+// failOnIOErrors is enabled.
+func (g *glob) handleIOError(op, name string, err error) (bool, error) {
+	err = pathError(op, name, err)
+	if g.errorHandler != nil {
+		switch e := g.errorHandler(name, err); e {
+		case nil:
+			g.collectError(name, err)
+			return false, nil
+		case SkipDir:
+			g.collectError(name, err)
+			return true, nil
+		default:
+			return true, e
+		}
+	}
+	if g.collectErrors {
+		g.collectError(name, err)
+		return true, nil
+	}
 	if g.failOnIOErrors {
+		return true, err
+	}
+	return true, nil
+}
+
+// Records `err` if WithCollectErrors is enabled, unless an error has already
+// been recorded for `name`.
+func (g *glob) collectError(name string, err error) {
+	if !g.collectErrors || g.errsPaths[name] {
+		return
+	}
+	if g.errsPaths == nil {
+		g.errsPaths = make(map[string]bool)
+	}
+	g.errsPaths[name] = true
+	g.errs = append(g.errs, err)
+}
+
+// Returns `err` if it is not nil. Otherwise, if WithCollectErrors is enabled
+// and any errors were collected, returns them as an *IOErrors.
+func (g *glob) collectedErrors(err error) error {
+	if err != nil || len(g.errs) == 0 {
 		return err
 	}
-	return nil
+	return &IOErrors{Errors: g.errs}
+}
+
+// Wraps `err` in a *fs.PathError for `name`, a path in the fs.FS being
+// globbed. If `err` is already a *fs.PathError, for example, with a path
+// that includes the root of an os.DirFS, it is rewrapped so that paths are
+// always relative to the fs.FS.
+func pathError(op, name string, err error) error {
+	if pe, ok := err.(*fs.PathError); ok {
+		return &fs.PathError{Op: pe.Op, Path: name, Err: pe.Err}
+	}
+	return &fs.PathError{Op: op, Path: name, Err: err}
 }
 
 // handleErrNotExist handles fs.ErrNotExist errors. If
@@ -141,6 +506,13 @@ func (g *glob) GoString() string {
 		b.WriteString("WithFilesOnly")
 		hasOpts = true
 	}
+	if g.dirsOnly {
+		if hasOpts {
+			b.WriteString(", ")
+		}
+		b.WriteString("WithDirsOnly")
+		hasOpts = true
+	}
 	if g.noFollow {
 		if hasOpts {
 			b.WriteString(", ")
@@ -148,6 +520,92 @@ func (g *glob) GoString() string {
 		b.WriteString("WithNoFollow")
 		hasOpts = true
 	}
+	if g.skipHidden {
+		if hasOpts {
+			b.WriteString(", ")
+		}
+		b.WriteString("WithSkipHidden")
+		hasOpts = true
+	}
+	if g.separator != 0 {
+		if hasOpts {
+			b.WriteString(", ")
+		}
+		b.WriteString("WithSeparator(")
+		b.WriteString(strconv.QuoteRune(g.separator))
+		b.WriteString(")")
+		hasOpts = true
+	}
+	if g.strictDoubleStar {
+		if hasOpts {
+			b.WriteString(", ")
+		}
+		b.WriteString("WithStrictDoubleStar")
+		hasOpts = true
+	}
+	if g.normalizer != nil {
+		if hasOpts {
+			b.WriteString(", ")
+		}
+		fmt.Fprintf(&b, "WithUnicodeNormalization(%v)", g.normalizer)
+		hasOpts = true
+	}
+	if g.byteSemantics {
+		if hasOpts {
+			b.WriteString(", ")
+		}
+		b.WriteString("WithByteSemantics")
+		hasOpts = true
+	}
+	if g.sorted {
+		if hasOpts {
+			b.WriteString(", ")
+		}
+		fmt.Fprintf(&b, "WithSortOrder(%d)", g.sortOrder)
+		hasOpts = true
+	}
+	if g.breadthFirst {
+		if hasOpts {
+			b.WriteString(", ")
+		}
+		b.WriteString("WithBreadthFirstTraversal")
+		hasOpts = true
+	}
+	if g.limit > 0 {
+		if hasOpts {
+			b.WriteString(", ")
+		}
+		fmt.Fprintf(&b, "WithLimit(%d)", g.limit)
+		hasOpts = true
+	}
+	if g.errorHandler != nil {
+		if hasOpts {
+			b.WriteString(", ")
+		}
+		b.WriteString("WithErrorHandler")
+		hasOpts = true
+	}
+	if g.collectErrors {
+		if hasOpts {
+			b.WriteString(", ")
+		}
+		b.WriteString("WithCollectErrors")
+		hasOpts = true
+	}
+	if g.stats != nil {
+		if hasOpts {
+			b.WriteString(", ")
+		}
+		b.WriteString("WithStats")
+		hasOpts = true
+	}
+	if g.trace != nil {
+		if hasOpts {
+			b.WriteString(", ")
+		}
+		b.WriteString("WithTrace")
+		hasOpts = true
+	}
 
 	if !hasOpts {
 		b.WriteString("nil")
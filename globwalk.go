@@ -6,6 +6,7 @@ import (
 	"path"
 	"path/filepath"
 	"strings"
+	"time"
 )
 
 // If returned from GlobWalkFunc, will cause GlobWalk to skip the current
@@ -51,12 +52,77 @@ type GlobWalkFunc func(path string, d fs.DirEntry) error
 // Note: users should _not_ count on the returned error,
 // doublestar.ErrBadPattern, being equal to path.ErrBadPattern.
 func GlobWalk(fsys fs.FS, pattern string, fn GlobWalkFunc, opts ...GlobOption) error {
-	if !ValidatePattern(pattern) {
+	return newGlob(opts...).globWalk(fsys, pattern, fn)
+}
+
+// GlobWalkOptions are the callbacks used by GlobWalkDir.
+type GlobWalkOptions struct {
+	// Enter, if not nil, is called with the path of a directory before
+	// GlobWalkDir reads it. If Enter returns SkipDir, the directory is not read,
+	// so nothing below it will be matched, though the directory itself may still
+	// match a pattern such as `**`. This makes it possible to prune directories
+	// that do not match the pattern themselves, but are known to be irrelevant.
+	// If Enter returns any other error, GlobWalkDir will exit immediately and
+	// return that error.
+	Enter func(dir string) error
+
+	// Leave, if not nil, is called with the path of a directory after
+	// GlobWalkDir has finished with it. Every call to Enter that did not return
+	// an error has a corresponding call to Leave, unless the walk is ended early
+	// by an error.
+	Leave func(dir string)
+
+	// Match, if not nil, is called for every file matching the pattern, just
+	// like the callback function passed to GlobWalk.
+	Match GlobWalkFunc
+}
+
+// GlobWalkDir is like GlobWalk, but calls the callbacks in `w` as it walks
+// the file system: Enter and Leave are called for every directory that
+// GlobWalkDir reads, whether the directory matches the pattern or not, and
+// Match is called for every match.
+//
+// Directories are only read if the pattern requires it. For example, given
+// the pattern `a/*/c`, GlobWalkDir will read the directory `a`, and then
+// every subdirectory of `a`, but it will not read `a/b/c`. Each directory is
+// entered and left once, even if a pattern such as `**/*.txt` needs to read it
+// more than once. Without
+// WithBreadthFirstTraversal, a directory is entered before, and left after,
+// any of its subdirectories, so Leave can be used for post-order processing.
+// If the pattern contains alts (ie, `{...}`), a directory may be entered more
+// than once. With WithSortOrder, all directories are entered and left before
+// Match is called for any match.
+func GlobWalkDir(fsys fs.FS, pattern string, w GlobWalkOptions, opts ...GlobOption) error {
+	g := newGlob(opts...)
+	g.enter = w.Enter
+	g.leave = w.Leave
+
+	fn := w.Match
+	if fn == nil {
+		fn = func(string, fs.DirEntry) error { return nil }
+	}
+	return g.globWalk(fsys, pattern, fn)
+}
+
+// Validates the pattern and runs the walk for GlobWalk and GlobWalkDir
+func (g *glob) globWalk(fsys fs.FS, pattern string, fn GlobWalkFunc) error {
+	if g.stats != nil {
+		defer addSince(&g.stats.TotalTime, time.Now())
+	}
+	if !g.validatePattern(pattern, '/') {
 		return ErrBadPattern
 	}
+	pattern = g.normalize(pattern)
 
-	g := newGlob(opts...)
-	return g.doGlobWalk(fsys, pattern, true, true, fn)
+	fn = g.limitWalkFunc(fn)
+	if g.sorted {
+		matches, err := g.doGlobSorted(fsys, pattern)
+		if err != nil {
+			return err
+		}
+		return g.collectedErrors(ignoreLimitReached(g.walkMatches(fsys, matches, fn)))
+	}
+	return g.collectedErrors(ignoreLimitReached(g.doGlobWalk(fsys, pattern, true, true, fn)))
 }
 
 // Actually execute GlobWalk
@@ -73,9 +139,9 @@ func (g *glob) doGlobWalk(fsys fs.FS, pattern string, firstSegment, beforeMeta b
 		// pattern doesn't contain any meta characters - does a file matching the
 		// pattern exist?
 		// The pattern may contain escaped wildcard characters for an exact path match.
-		path := unescapeMeta(pattern)
+		path := g.resolveNormalized(fsys, g.unescapeMeta(pattern))
 		info, pathExists, err := g.exists(fsys, path, beforeMeta)
-		if pathExists && (!firstSegment || !g.filesOnly || !info.IsDir()) {
+		if pathExists && (info.IsDir() || !isDirPattern(path)) && (!firstSegment || g.matchesFileType(info.IsDir())) {
 			err = fn(path, dirEntryFromFileInfo(info))
 			if err == SkipDir {
 				err = nil
@@ -111,7 +177,7 @@ func (g *glob) doGlobWalk(fsys fs.FS, pattern string, firstSegment, beforeMeta b
 	// characters. They would be equal if they are both -1, which means `dir`
 	// will be ".", and we know that doesn't have meta characters either.
 	if splitIdx <= patternStart {
-		return g.globDirWalk(fsys, unescapeMeta(dir), pattern, firstSegment, beforeMeta, fn)
+		return g.globDirWalk(fsys, g.resolveNormalized(fsys, g.unescapeMeta(dir)), pattern, firstSegment, beforeMeta, fn)
 	}
 
 	return g.doGlobWalk(fsys, dir, false, beforeMeta, func(p string, d fs.DirEntry) error {
@@ -205,6 +271,7 @@ func (g *glob) doGlobAltsWalk(fsys fs.FS, d, pattern string, startIdx, openingId
 			nextIdx += patIdx
 		}
 
+		g.countAltExpanded()
 		alt := buildAlt(d, pattern, startIdx, openingIdx, patIdx, nextIdx, afterIdx)
 		err = g.doGlobWalk(fsys, alt, firstSegment, beforeMeta, func(p string, d fs.DirEntry) error {
 			// insertion sort, ignoring dups
@@ -267,43 +334,71 @@ func (g *glob) globDirWalk(fsys fs.FS, dir, pattern string, canMatchFiles, befor
 		if !dirExists || !info.IsDir() {
 			return nil
 		}
+		enter, err := g.enterDir(dir)
+		if err != nil {
+			return err
+		}
 		if !canMatchFiles || !g.filesOnly {
 			if e = fn(dir, dirEntryFromFileInfo(info)); e != nil {
 				if e == SkipDir {
 					e = nil
+					if enter {
+						g.countDirPruned()
+						g.leaveDir(dir)
+					}
 				}
 				return
 			}
 		}
-		return g.globDoubleStarWalk(fsys, dir, canMatchFiles, fn)
+		if !enter {
+			return nil
+		}
+		if g.breadthFirst {
+			// leaves `dir` itself
+			return g.globDoubleStarWalkBreadthFirst(fsys, dir, canMatchFiles, fn)
+		}
+		if e = g.globDoubleStarWalk(fsys, dir, canMatchFiles, fn); e == nil {
+			g.leaveDir(dir)
+		}
+		return
 	}
 
-	dirs, err := fs.ReadDir(fsys, dir)
+	if enter, err := g.enterDir(dir); !enter {
+		return err
+	}
+	if g.enter != nil || g.leave != nil {
+		defer g.leaveDirOnSuccess(dir, &e)
+	}
+
+	dirs, err := g.readDir(fsys, dir)
 	if err != nil {
 		if errors.Is(err, fs.ErrNotExist) {
 			return g.handlePatternNotExist(beforeMeta)
 		}
-		return g.forwardErrIfFailOnIOErrors(err)
+		if skip, err := g.handleIOError("readdir", dir, err); skip {
+			return err
+		}
 	}
 
 	var matched bool
 	for _, info := range dirs {
 		name := info.Name()
-		matched, e = matchWithSeparator(pattern, name, '/', false, g.caseInsensitive)
+		matched, e = g.matchEntry(pattern, dir, name)
 		if e != nil {
 			return
 		}
 		if matched {
 			matched = canMatchFiles
-			if !matched || g.filesOnly {
-				matched, e = g.isDir(fsys, dir, name, info)
+			if !matched || g.filesOnly || g.dirsOnly {
+				var isDir bool
+				isDir, e = g.isDir(fsys, dir, name, info)
 				if e != nil {
 					return e
 				}
+				matched = isDir
 				if canMatchFiles {
-					// if we're here, it's because g.filesOnly
-					// is set and we don't want directories
-					matched = !matched
+					// if we're here, it's because g.filesOnly or g.dirsOnly is set
+					matched = g.matchesFileType(isDir)
 				}
 			}
 			if matched {
@@ -321,8 +416,10 @@ func (g *glob) globDirWalk(fsys fs.FS, dir, pattern string, canMatchFiles, befor
 }
 
 // recursively walk files/directories in a directory
+//   - `dir` must have been entered with enterDir; the caller is responsible for
+//     leaving it
 func (g *glob) globDoubleStarWalk(fsys fs.FS, dir string, canMatchFiles bool, fn GlobWalkFunc) (e error) {
-	dirs, err := fs.ReadDir(fsys, dir)
+	dirs, err := g.readDir(fsys, dir)
 	if err != nil {
 		if errors.Is(err, fs.ErrNotExist) {
 			// This function is only ever called after we know the top-most directory
@@ -330,11 +427,20 @@ func (g *glob) globDoubleStarWalk(fsys fs.FS, dir string, canMatchFiles bool, fn
 			// ErrPatternNotExist.
 			return nil
 		}
-		return g.forwardErrIfFailOnIOErrors(err)
+		if skip, err := g.handleIOError("readdir", dir, err); skip {
+			return err
+		}
 	}
 
 	for _, info := range dirs {
 		name := info.Name()
+		if g.isHidden(name) {
+			// `**` cannot match hidden files or directories
+			if info.IsDir() {
+				g.countDirPruned()
+			}
+			continue
+		}
 		isDir, err := g.isDir(fsys, dir, name, info)
 		if err != nil {
 			return err
@@ -342,20 +448,31 @@ func (g *glob) globDoubleStarWalk(fsys fs.FS, dir string, canMatchFiles bool, fn
 
 		if isDir {
 			p := path.Join(dir, name)
+			enter, err := g.enterDir(p)
+			if err != nil {
+				return err
+			}
 			if !canMatchFiles || !g.filesOnly {
 				// `**` can match *this* dir, so add it
 				if e = fn(p, info); e != nil {
 					if e == SkipDir {
 						e = nil
+						if enter {
+							g.countDirPruned()
+							g.leaveDir(p)
+						}
 						continue
 					}
 					return
 				}
 			}
-			if e = g.globDoubleStarWalk(fsys, p, canMatchFiles, fn); e != nil {
-				return
+			if enter {
+				if e = g.globDoubleStarWalk(fsys, p, canMatchFiles, fn); e != nil {
+					return
+				}
+				g.leaveDir(p)
 			}
-		} else if canMatchFiles {
+		} else if canMatchFiles && !g.dirsOnly {
 			if e = fn(path.Join(dir, name), info); e != nil {
 				if e == SkipDir {
 					e = nil
@@ -368,6 +485,152 @@ func (g *glob) globDoubleStarWalk(fsys fs.FS, dir string, canMatchFiles bool, fn
 	return
 }
 
+// walk files/directories in a directory breadth-first, keeping a queue of
+// directories that have not been read yet
+//   - `root` must have been entered with enterDir; every queued directory,
+//     including `root`, is left once it has been read
+func (g *glob) globDoubleStarWalkBreadthFirst(fsys fs.FS, root string, canMatchFiles bool, fn GlobWalkFunc) (e error) {
+	queue := []string{root}
+	for len(queue) > 0 {
+		dir := queue[0]
+		queue[0] = ""
+		queue = queue[1:]
+
+		dirs, err := g.readDir(fsys, dir)
+		if err != nil {
+			// See globDoubleStarWalk: we already know the top-most directory exists,
+			// so this can never be ErrPatternNotExist.
+			skip := true
+			if !errors.Is(err, fs.ErrNotExist) {
+				if skip, e = g.handleIOError("readdir", dir, err); e != nil {
+					return
+				}
+			}
+			if skip {
+				g.leaveDir(dir)
+				continue
+			}
+		}
+
+	entries:
+		for _, info := range dirs {
+			name := info.Name()
+			if g.isHidden(name) {
+				// `**` cannot match hidden files or directories
+				if info.IsDir() {
+					g.countDirPruned()
+				}
+				continue
+			}
+			isDir, err := g.isDir(fsys, dir, name, info)
+			if err != nil {
+				return err
+			}
+
+			if isDir {
+				p := path.Join(dir, name)
+				enter, err := g.enterDir(p)
+				if err != nil {
+					return err
+				}
+				if !canMatchFiles || !g.filesOnly {
+					// `**` can match *this* dir, so add it
+					if e = fn(p, info); e != nil {
+						if e == SkipDir {
+							e = nil
+							if enter {
+								g.countDirPruned()
+								g.leaveDir(p)
+							}
+							continue
+						}
+						return
+					}
+				}
+				if enter {
+					queue = append(queue, p)
+				}
+			} else if canMatchFiles && !g.dirsOnly {
+				if e = fn(path.Join(dir, name), info); e != nil {
+					if e == SkipDir {
+						// skip the rest of this directory, but directories that were
+						// already queued are still walked, just as they would have been
+						// depth-first
+						e = nil
+						break entries
+					}
+					return
+				}
+			}
+		}
+		g.leaveDir(dir)
+	}
+
+	return
+}
+
+// Calls the Enter callback set by GlobWalkDir, unless `dir` has already been
+// entered and not left yet, which happens when a directory is read by `**` and
+// then again to match the next segment of the pattern. Returns true if `dir`
+// should be read, in which case the caller must call leaveDir once it is done
+// with `dir`, unless the walk is ending with an error. Returns false if the
+// callback returned SkipDir, now or any time earlier in the walk. If the
+// callback returned any other error, it is returned.
+func (g *glob) enterDir(dir string) (bool, error) {
+	if g.enter == nil && g.leave == nil {
+		return true, nil
+	}
+	if g.activeDirs[dir] > 0 {
+		g.activeDirs[dir]++
+		return true, nil
+	}
+	if g.skippedDirs[dir] {
+		return false, nil
+	}
+
+	if g.enter != nil {
+		if err := g.enter(dir); err != nil {
+			if err != SkipDir {
+				return false, err
+			}
+			if g.skippedDirs == nil {
+				g.skippedDirs = make(map[string]bool)
+			}
+			g.skippedDirs[dir] = true
+			g.countDirPruned()
+			return false, nil
+		}
+	}
+	if g.activeDirs == nil {
+		g.activeDirs = make(map[string]int)
+	}
+	g.activeDirs[dir] = 1
+	return true, nil
+}
+
+// Undoes enterDir, calling the Leave callback set by GlobWalkDir once `dir` is
+// no longer active.
+func (g *glob) leaveDir(dir string) {
+	if g.enter == nil && g.leave == nil {
+		return
+	}
+	if n := g.activeDirs[dir] - 1; n > 0 {
+		g.activeDirs[dir] = n
+		return
+	}
+	delete(g.activeDirs, dir)
+	if g.leave != nil {
+		g.leave(dir)
+	}
+}
+
+// Calls leaveDir if `*e` is nil. Meant to be deferred.
+func (g *glob) leaveDirOnSuccess(dir string, e *error) {
+	if *e == nil {
+		g.leaveDir(dir)
+	}
+}
+
 type DirEntryFromFileInfo struct {
 	fi fs.FileInfo
 }
@@ -0,0 +1,186 @@
+package doublestar
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"path"
+	"sort"
+	"time"
+)
+
+// Lister is a minimal interface for trees that are not an fs.FS, such as key
+// listings in object storage, git trees, or catalogs stored in a database. It
+// only needs to be able to list the children of a directory. GlobLister and
+// GlobWalkLister glob a Lister the same way Glob and GlobWalk glob an fs.FS.
+type Lister interface {
+	// List returns the children of `dir`, a slash-separated path relative to
+	// the root of the tree, such as "a/b". The root is ".". The entries do
+	// not need to be sorted. If `dir` does not exist, List should either
+	// return an error wrapping fs.ErrNotExist or no entries. Other errors are
+	// treated like I/O errors: see WithFailOnIOErrors.
+	List(ctx context.Context, dir string) ([]Entry, error)
+}
+
+// StatLister may be implemented by a Lister that can look up a single path
+// more efficiently than by listing its parent. If a Lister does not implement
+// StatLister, GlobLister and GlobWalkLister list the parent directory instead.
+type StatLister interface {
+	Lister
+
+	// Stat returns information about `name`. If `name` is a symlink, Stat
+	// should return information about its target, just like fs.Stat. If
+	// `name` does not exist, the error must wrap fs.ErrNotExist.
+	Stat(ctx context.Context, name string) (fs.FileInfo, error)
+}
+
+// Entry is a child of a directory returned by Lister.List.
+type Entry struct {
+	// Name is the name of the child, without the directory, such as "c.go".
+	Name string
+
+	// Type is the type bits of the child, as returned by fs.DirEntry.Type:
+	// fs.ModeDir for a directory, or 0 for a regular file. If it is
+	// fs.ModeSymlink, the child is followed (unless WithNoFollow is passed)
+	// by calling Stat, so the Lister should implement StatLister.
+	Type fs.FileMode
+}
+
+// FSLister returns a Lister that lists the directories of `fsys` with
+// fs.ReadDir. It implements StatLister with fs.Stat, so GlobLister and
+// GlobWalkLister return the same results for FSLister(fsys) as Glob and
+// GlobWalk do for `fsys`.
+func FSLister(fsys fs.FS) Lister {
+	return fsLister{fsys}
+}
+
+type fsLister struct {
+	fsys fs.FS
+}
+
+func (l fsLister) List(ctx context.Context, dir string) ([]Entry, error) {
+	entries, err := fs.ReadDir(l.fsys, dir)
+	if err != nil {
+		return nil, err
+	}
+	children := make([]Entry, len(entries))
+	for i, e := range entries {
+		children[i] = Entry{Name: e.Name(), Type: e.Type()}
+	}
+	return children, nil
+}
+
+func (l fsLister) Stat(ctx context.Context, name string) (fs.FileInfo, error) {
+	return fs.Stat(l.fsys, name)
+}
+
+// GlobLister is like Glob, but globs a Lister instead of an fs.FS. All of
+// the same options may be passed, and patterns behave identically: in
+// particular, directories that cannot contain a match are never listed.
+//
+// `ctx` is passed to every call to List and Stat. If `ctx` is done before the
+// glob completes, GlobLister returns nil and ctx.Err().
+func GlobLister(ctx context.Context, lister Lister, pattern string, opts ...GlobOption) ([]string, error) {
+	matches, err := Glob(newListerFS(ctx, lister), pattern, opts...)
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+	return matches, err
+}
+
+// GlobWalkLister is like GlobWalk, but globs a Lister instead of an fs.FS.
+// The fs.DirEntry passed to `fn` is built from the Entry returned by List:
+// its Info() only reports the name and type.
+//
+// `ctx` is passed to every call to List and Stat. If `ctx` is done before the
+// walk completes, `fn` is not called again and GlobWalkLister returns
+// ctx.Err().
+func GlobWalkLister(ctx context.Context, lister Lister, pattern string, fn GlobWalkFunc, opts ...GlobOption) error {
+	err := GlobWalk(newListerFS(ctx, lister), pattern, func(p string, d fs.DirEntry) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		return fn(p, d)
+	}, opts...)
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	return err
+}
+
+// listerFS adapts a Lister to an fs.FS with ReadDir and Stat, which is all
+// that globbing needs. Listings are remembered for the duration of a single
+// glob, since Stat may need to list the same parent repeatedly.
+type listerFS struct {
+	ctx    context.Context
+	lister Lister
+	dirs   map[string][]fs.DirEntry
+}
+
+func newListerFS(ctx context.Context, lister Lister) *listerFS {
+	return &listerFS{ctx: ctx, lister: lister, dirs: make(map[string][]fs.DirEntry)}
+}
+
+func (l *listerFS) Open(name string) (fs.File, error) {
+	return nil, &fs.PathError{Op: "open", Path: name, Err: errListerOpen}
+}
+
+var errListerOpen = errors.New("a Lister cannot be opened")
+
+func (l *listerFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	if entries, ok := l.dirs[name]; ok {
+		return copyDirEntries(entries), nil
+	}
+	if err := l.ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	children, err := l.lister.List(l.ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	entries := make([]fs.DirEntry, len(children))
+	for i, child := range children {
+		entries[i] = listerEntry{child}
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name() < entries[j].Name() })
+	l.dirs[name] = entries
+	return copyDirEntries(entries), nil
+}
+
+func (l *listerFS) Stat(name string) (fs.FileInfo, error) {
+	if err := l.ctx.Err(); err != nil {
+		return nil, err
+	}
+	if sl, ok := l.lister.(StatLister); ok {
+		return sl.Stat(l.ctx, name)
+	}
+	if name == "." {
+		return listerEntry{Entry{Name: ".", Type: fs.ModeDir}}, nil
+	}
+
+	entries, err := l.ReadDir(path.Dir(name))
+	if err != nil {
+		return nil, err
+	}
+	base := path.Base(name)
+	i := sort.Search(len(entries), func(i int) bool { return entries[i].Name() >= base })
+	if i < len(entries) && entries[i].Name() == base {
+		return entries[i].Info()
+	}
+	return nil, &fs.PathError{Op: "stat", Path: name, Err: fs.ErrNotExist}
+}
+
+// listerEntry implements both fs.DirEntry and fs.FileInfo for an Entry
+type listerEntry struct {
+	entry Entry
+}
+
+func (e listerEntry) Name() string               { return e.entry.Name }
+func (e listerEntry) IsDir() bool                { return e.entry.Type.IsDir() }
+func (e listerEntry) Type() fs.FileMode          { return e.entry.Type }
+func (e listerEntry) Info() (fs.FileInfo, error) { return e, nil }
+func (e listerEntry) Size() int64                { return 0 }
+func (e listerEntry) Mode() fs.FileMode          { return e.entry.Type }
+func (e listerEntry) ModTime() time.Time         { return time.Time{} }
+func (e listerEntry) Sys() interface{}           { return nil }
@@ -0,0 +1,137 @@
+package doublestar
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"strings"
+	"testing"
+)
+
+// keyLister lists a flat set of keys, like object storage, where directories
+// only exist because keys have them as a prefix. It does not implement
+// StatLister.
+type keyLister struct {
+	keys   []string
+	listed map[string]int
+	err    map[string]error
+}
+
+func (k *keyLister) List(ctx context.Context, dir string) ([]Entry, error) {
+	k.listed[dir]++
+	if err := k.err[dir]; err != nil {
+		return nil, err
+	}
+
+	prefix := dir + "/"
+	if dir == "." {
+		prefix = ""
+	}
+	seen := make(map[string]bool)
+	var entries []Entry
+	for _, key := range k.keys {
+		if !strings.HasPrefix(key, prefix) {
+			continue
+		}
+		name := key[len(prefix):]
+		var typ fs.FileMode
+		if i := strings.IndexByte(name, '/'); i != -1 {
+			name, typ = name[:i], fs.ModeDir
+		}
+		if !seen[name] {
+			seen[name] = true
+			entries = append(entries, Entry{Name: name, Type: typ})
+		}
+	}
+	return entries, nil
+}
+
+func newKeyLister(keys ...string) *keyLister {
+	return &keyLister{keys: keys, listed: make(map[string]int), err: make(map[string]error)}
+}
+
+func TestGlobLister(t *testing.T) {
+	lister := newKeyLister("a/b/c.go", "a/b/d.txt", "a/e/c.go", "x/y/c.go", "z.go")
+
+	tests := []struct {
+		pattern  string
+		expected []string
+		listed   []string
+		opts     []GlobOption
+	}{
+		// without a StatLister, checking that a path exists lists its parent
+		{"a/**/*.go", []string{"a/b/c.go", "a/e/c.go"}, []string{".", "a", "a/b", "a/e"}, nil},
+		{"*/b/*", []string{"a/b/c.go", "a/b/d.txt"}, []string{".", "a", "a/b", "x"}, nil},
+		{"a/b/c.go", []string{"a/b/c.go"}, []string{"a/b"}, nil},
+		{"a/b/nope.go", nil, []string{"a/b"}, nil},
+		{"{a,x}/*/c.go", []string{"a/b/c.go", "a/e/c.go", "x/y/c.go"}, []string{".", "a", "a/b", "a/e", "x", "x/y"}, nil},
+		{"*", []string{"a", "x", "z.go"}, []string{"."}, nil},
+		{"*", []string{"z.go"}, []string{"."}, []GlobOption{WithFilesOnly()}},
+		{"a/*/", []string{"a/b", "a/e"}, []string{"a"}, nil},
+		{"**/c.go", []string{"a/b/c.go", "a/e/c.go", "x/y/c.go"}, []string{".", "a", "a/b", "a/e", "x", "x/y"}, nil},
+	}
+
+	for idx, tt := range tests {
+		lister.listed = make(map[string]int)
+		matches, err := GlobLister(context.Background(), lister, tt.pattern, tt.opts...)
+		if err != nil {
+			t.Errorf("#%v. GlobLister(%#q) error: %v", idx, tt.pattern, err)
+			continue
+		}
+		if !compareSlices(matches, tt.expected) {
+			t.Errorf("#%v. GlobLister(%#q) = %v want %v", idx, tt.pattern, matches, tt.expected)
+		}
+		for _, dir := range tt.listed {
+			if lister.listed[dir] != 1 {
+				t.Errorf("#%v. GlobLister(%#q) listed %#q %v times want 1", idx, tt.pattern, dir, lister.listed[dir])
+			}
+		}
+		if len(lister.listed) != len(tt.listed) {
+			t.Errorf("#%v. GlobLister(%#q) listed %v want %v", idx, tt.pattern, lister.listed, tt.listed)
+		}
+
+		var walked []string
+		err = GlobWalkLister(context.Background(), lister, tt.pattern, func(p string, d fs.DirEntry) error {
+			walked = append(walked, p)
+			return nil
+		}, tt.opts...)
+		if err != nil || !compareSlices(walked, tt.expected) {
+			t.Errorf("#%v. GlobWalkLister(%#q) = %v, %v want %v", idx, tt.pattern, walked, err, tt.expected)
+		}
+	}
+}
+
+func TestGlobListerErrors(t *testing.T) {
+	lister := newKeyLister("a/b/c.go", "x/y/c.go")
+	lister.err["a"] = errTest
+
+	matches, err := GlobLister(context.Background(), lister, "*/*/c.go")
+	if err != nil || !compareSlices(matches, []string{"x/y/c.go"}) {
+		t.Errorf("GlobLister(`*/*/c.go`) = %v, %v want [x/y/c.go]", matches, err)
+	}
+	if _, err = GlobLister(context.Background(), lister, "*/*/c.go", WithFailOnIOErrors()); !errors.Is(err, errTest) {
+		t.Errorf("GlobLister(`*/*/c.go`, WithFailOnIOErrors()) error = %v want %v", err, errTest)
+	}
+	if _, err = GlobLister(context.Background(), lister, "a/["); err != ErrBadPattern {
+		t.Errorf("GlobLister(`a/[`) error = %v want ErrBadPattern", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if matches, err = GlobLister(ctx, lister, "**"); matches != nil || err != context.Canceled {
+		t.Errorf("GlobLister(canceled, `**`) = %v, %v want context.Canceled", matches, err)
+	}
+
+	// canceling during a walk stops it
+	ctx, cancel = context.WithCancel(context.Background())
+	defer cancel()
+	calls := 0
+	err = GlobWalkLister(ctx, lister, "**", func(p string, d fs.DirEntry) error {
+		calls++
+		cancel()
+		return nil
+	})
+	if err != context.Canceled || calls != 1 {
+		t.Errorf("GlobWalkLister(canceled, `**`) = %v after %v calls want context.Canceled after 1", err, calls)
+	}
+}
@@ -88,17 +88,129 @@ func PathMatchUnvalidated(pattern, name string) bool {
 	return matched
 }
 
+// MatchWithOptions is like Match, but accepts options that alter how `pattern`
+// is matched against `name`. For example, passing WithSkipHidden will cause
+// MatchWithOptions to follow the same rules for hidden files and directories
+// as Glob would with the same option. Likewise, WithCaseInsensitive,
+// WithSeparator, and WithStrictDoubleStar may be passed.
+//
+// Options that only make sense while traversing a file system, such as
+// WithFilesOnly or WithNoFollow, are ignored. To match files or directories
+// only, see MatchDir.
+func MatchWithOptions(pattern, name string, opts ...MatchOption) (bool, error) {
+	g := newGlob(opts...)
+	return g.matchWithSeparator(g.normalize(pattern), g.normalize(name), g.separatorOrDefault('/'), true)
+}
+
+// PathMatchWithOptions is like PathMatch, but accepts options that alter how
+// `pattern` is matched against `name`, just like MatchWithOptions. Unless the
+// WithSeparator option is passed, your system's path separator is used to
+// split `name` and `pattern`.
+func PathMatchWithOptions(pattern, name string, opts ...MatchOption) (bool, error) {
+	g := newGlob(opts...)
+	return g.matchWithSeparator(g.normalize(pattern), g.normalize(name), g.separatorOrDefault(filepath.Separator), true)
+}
+
+// MatchDir is like MatchWithOptions, but also takes into account whether
+// `name` is a directory. This is useful when filtering lists of paths that
+// did not come from a file system, such as the contents of an archive or a
+// git tree, so that they are matched the same way Glob and GlobWalk would
+// match them.
+//
+// Just like Glob and GlobWalk, a pattern that ends in a path separator, such
+// as `build/`, only matches directories: MatchDir("build/", "build", true)
+// returns true, but MatchDir("build/", "build", false) returns false. If
+// `name` ends in a path separator, it is assumed to be a directory, no matter
+// what `isDir` is. Other patterns match files and directories alike, unless
+// WithFilesOnly or WithDirsOnly is passed.
+func MatchDir(pattern, name string, isDir bool, opts ...MatchOption) (bool, error) {
+	g := newGlob(opts...)
+	separator := g.separatorOrDefault('/')
+	pattern = g.normalize(pattern)
+	name = g.normalize(name)
+
+	dirPattern := false
+	if trimmed, ok := trimTrailingSeparator(pattern, separator, separator != '\\'); ok {
+		pattern = trimmed
+		dirPattern = true
+	}
+	if trimmed, ok := trimTrailingSeparator(name, separator, false); ok {
+		name = trimmed
+		isDir = true
+	}
+
+	matched, err := g.matchWithSeparator(pattern, name, separator, true)
+	if err != nil || !matched {
+		return false, err
+	}
+	return (isDir || !dirPattern) && g.matchesFileType(isDir), nil
+}
+
+// MatchWithSeparator is like Match, but splits `pattern` and `name` on
+// `separator` instead of `/`. This makes it possible to use glob syntax for
+// things that are not file paths, such as dotted configuration keys
+// (`service.*.timeout` or `metrics.**.p99`) or Java package names: `*` and `?`
+// will not match the separator, and `**` matches zero or more segments.
+//
+// `\` escapes the next character, unless the separator is `\`, in which case
+// escaping is disabled, just like PathMatch on Windows. Because every
+// occurrence of the separator in `name` splits it, an escaped separator in
+// `pattern` still matches the separator. Meta characters (`*`, `?`, `[`, `]`,
+// `{`, `}`, and `,`) cannot be used as the separator: MatchWithSeparator will
+// always return ErrBadPattern if they are.
+//
+// To combine a custom separator with other options, pass WithSeparator to
+// MatchWithOptions. Patterns can be validated ahead of time with
+// ValidatePatternWithSeparator.
+func MatchWithSeparator(pattern, name string, separator rune) (bool, error) {
+	return matchWithSeparator(pattern, name, separator, true, false)
+}
+
+// If `s` ends in `separator`, and is not just the separator by itself, returns
+// `s` without it and true. If `escapable` is true and the separator is
+// escaped, the escape is removed, too: an escaped separator still matches the
+// separator.
+func trimTrailingSeparator(s string, separator rune, escapable bool) (string, bool) {
+	r, size := utf8.DecodeLastRuneInString(s)
+	if r != separator || len(s) == size {
+		return s, false
+	}
+	trimmed := s[:len(s)-size]
+	if escapable {
+		escapes := 0
+		for i := len(trimmed) - 1; i >= 0 && trimmed[i] == '\\'; i-- {
+			escapes++
+		}
+		if escapes%2 == 1 {
+			trimmed = trimmed[:len(trimmed)-1]
+		}
+	}
+	return trimmed, true
+}
+
 func matchWithSeparator(pattern, name string, separator rune, validate bool, caseInsensitive bool) (matched bool, err error) {
-	return doMatchWithSeparator(pattern, name, separator, validate, caseInsensitive, -1, -1, -1, -1, 0, 0)
+	g := glob{caseInsensitive: caseInsensitive}
+	return g.matchWithSeparator(pattern, name, separator, validate)
+}
+
+func (g *glob) matchWithSeparator(pattern, name string, separator rune, validate bool) (matched bool, err error) {
+	if !isValidSeparator(separator) {
+		return false, ErrBadPattern
+	}
+	if validate && g.strictDoubleStar && !validateDoubleStars(pattern, separator) {
+		return false, ErrBadPattern
+	}
+	return g.doMatchWithSeparator(pattern, name, separator, validate, -1, -1, -1, -1, 0, 0)
 }
 
-func doMatchWithSeparator(pattern, name string, separator rune, validate bool, caseInsensitive bool, doublestarPatternBacktrack, doublestarNameBacktrack, starPatternBacktrack, starNameBacktrack, patIdx, nameIdx int) (matched bool, err error) {
+func (g *glob) doMatchWithSeparator(pattern, name string, separator rune, validate bool, doublestarPatternBacktrack, doublestarNameBacktrack, starPatternBacktrack, starNameBacktrack, patIdx, nameIdx int) (matched bool, err error) {
 	patLen := len(pattern)
 	nameLen := len(name)
 	startOfSegment := true
+	escaped := false
 MATCH:
 	for nameIdx < nameLen {
-		if patIdx < patLen {
+		if patIdx < patLen && g.canMatchHidden(pattern, name, patIdx, nameIdx, separator) {
 			switch pattern[patIdx] {
 			case '*':
 				if patIdx++; patIdx < patLen && pattern[patIdx] == '*' {
@@ -107,8 +219,12 @@ MATCH:
 					patIdx++
 					if startOfSegment {
 						if patIdx >= patLen {
-							// pattern ends in `/**`: return true
-							return true, nil
+							// pattern ends in `/**`: return true, unless the rest of `name`
+							// contains hidden segments that `**` isn't allowed to match
+							if !g.skipHidden || !hasHiddenSegment(name[nameIdx:], separator) {
+								return true, nil
+							}
+							break
 						}
 
 						// doublestar must also end with a path separator, otherwise we're
@@ -133,7 +249,7 @@ MATCH:
 
 			case '?':
 				startOfSegment = false
-				nameRune, nameRuneLen := utf8.DecodeRuneInString(name[nameIdx:])
+				nameRune, nameRuneLen := g.decodeRune(name[nameIdx:])
 				if nameRune == separator {
 					// `?` cannot match the separator
 					break
@@ -149,7 +265,7 @@ MATCH:
 					// class didn't end
 					return false, ErrBadPattern
 				}
-				nameRune, nameRuneLen := utf8.DecodeRuneInString(name[nameIdx:])
+				nameRune, nameRuneLen := g.decodeRune(name[nameIdx:])
 
 				matched := false
 				negate := pattern[patIdx] == '!' || pattern[patIdx] == '^'
@@ -164,19 +280,20 @@ MATCH:
 
 				last := utf8.MaxRune
 				for patIdx < patLen && pattern[patIdx] != ']' {
-					patRune, patRuneLen := utf8.DecodeRuneInString(pattern[patIdx:])
+					patRune, patRuneLen := g.decodeRune(pattern[patIdx:])
 					patIdx += patRuneLen
 
 					// match a range
 					if last < utf8.MaxRune && patRune == '-' && patIdx < patLen && pattern[patIdx] != ']' {
-						if pattern[patIdx] == '\\' {
+						escapedHi := pattern[patIdx] == '\\'
+						if escapedHi {
 							// next character is escaped
 							patIdx++
 						}
-						patRune, patRuneLen = utf8.DecodeRuneInString(pattern[patIdx:])
+						patRune, patRuneLen = g.decodePatternRune(pattern[patIdx:], escapedHi)
 						patIdx += patRuneLen
 
-						if last <= nameRune && nameRune <= patRune {
+						if g.matchRange(last, patRune, nameRune) {
 							matched = true
 							break
 						}
@@ -188,12 +305,12 @@ MATCH:
 
 					// not a range - check if the next rune is escaped
 					if patRune == '\\' {
-						patRune, patRuneLen = utf8.DecodeRuneInString(pattern[patIdx:])
+						patRune, patRuneLen = g.decodePatternRune(pattern[patIdx:], true)
 						patIdx += patRuneLen
 					}
 
 					// check if the rune matches
-					if matchRune(patRune, nameRune, caseInsensitive) {
+					if g.matchRune(patRune, nameRune) {
 						matched = true
 						break
 					}
@@ -239,14 +356,14 @@ MATCH:
 					}
 					commaIdx += patIdx
 
-					result, err := doMatchWithSeparator(pattern[:beforeIdx]+pattern[patIdx:commaIdx]+pattern[closingIdx+1:], name, separator, validate, caseInsensitive, doublestarPatternBacktrack, doublestarNameBacktrack, starPatternBacktrack, starNameBacktrack, beforeIdx, nameIdx)
+					result, err := g.doMatchWithSeparator(pattern[:beforeIdx]+pattern[patIdx:commaIdx]+pattern[closingIdx+1:], name, separator, validate, doublestarPatternBacktrack, doublestarNameBacktrack, starPatternBacktrack, starNameBacktrack, beforeIdx, nameIdx)
 					if result || err != nil {
 						return result, err
 					}
 
 					patIdx = commaIdx + 1
 				}
-				return doMatchWithSeparator(pattern[:beforeIdx]+pattern[patIdx:closingIdx]+pattern[closingIdx+1:], name, separator, validate, caseInsensitive, doublestarPatternBacktrack, doublestarNameBacktrack, starPatternBacktrack, starNameBacktrack, beforeIdx, nameIdx)
+				return g.doMatchWithSeparator(pattern[:beforeIdx]+pattern[patIdx:closingIdx]+pattern[closingIdx+1:], name, separator, validate, doublestarPatternBacktrack, doublestarNameBacktrack, starPatternBacktrack, starNameBacktrack, beforeIdx, nameIdx)
 
 			case '\\':
 				if separator != '\\' {
@@ -255,13 +372,15 @@ MATCH:
 						// pattern ended
 						return false, ErrBadPattern
 					}
+					escaped = true
 				}
 				fallthrough
 
 			default:
-				patRune, patRuneLen := utf8.DecodeRuneInString(pattern[patIdx:])
-				nameRune, nameRuneLen := utf8.DecodeRuneInString(name[nameIdx:])
-				if !matchRune(patRune, nameRune, caseInsensitive) {
+				patRune, patRuneLen := g.decodePatternRune(pattern[patIdx:], escaped)
+				nameRune, nameRuneLen := g.decodeRune(name[nameIdx:])
+				escaped = false
+				if !g.matchRune(patRune, nameRune) {
 					if separator != '\\' && patIdx > 0 && pattern[patIdx-1] == '\\' {
 						// if this rune was meant to be escaped, we need to move patIdx
 						// back to the backslash before backtracking or validating below
@@ -279,7 +398,7 @@ MATCH:
 
 		if starPatternBacktrack >= 0 {
 			// `*` backtrack, but only if the `name` rune isn't the separator
-			nameRune, nameRuneLen := utf8.DecodeRuneInString(name[starNameBacktrack:])
+			nameRune, nameRuneLen := g.decodeRune(name[starNameBacktrack:])
 			if nameRune != separator {
 				starNameBacktrack += nameRuneLen
 				patIdx = starPatternBacktrack
@@ -289,11 +408,12 @@ MATCH:
 			}
 		}
 
-		if doublestarPatternBacktrack >= 0 {
-			// `**` backtrack, advance `name` past next separator
+		if doublestarPatternBacktrack >= 0 && (!g.skipHidden || name[doublestarNameBacktrack] != '.') {
+			// `**` backtrack, advance `name` past next separator - `**` cannot
+			// consume hidden segments if WithSkipHidden is enabled
 			nameIdx = doublestarNameBacktrack
 			for nameIdx < nameLen {
-				nameRune, nameRuneLen := utf8.DecodeRuneInString(name[nameIdx:])
+				nameRune, nameRuneLen := g.decodeRune(name[nameIdx:])
 				nameIdx += nameRuneLen
 				if nameRune == separator {
 					doublestarNameBacktrack = nameIdx
@@ -316,11 +436,157 @@ MATCH:
 	return isZeroLengthPattern(pattern[patIdx:], separator, validate)
 }
 
+// Decodes the first character in s. If WithByteSemantics is enabled, this is
+// always a single byte. Otherwise, it is a UTF-8 encoded rune.
+func (g *glob) decodeRune(s string) (rune, int) {
+	if g.byteSemantics && len(s) > 0 {
+		return rune(s[0]), 1
+	}
+	return utf8.DecodeRuneInString(s)
+}
+
+// Decodes the first character in s, which is part of a pattern. If `escaped`
+// is true, the character was preceded by a backslash. If WithByteSemantics is
+// enabled, escaped characters may be a `\xNN` escape, representing a single
+// byte in hexadecimal.
+func (g *glob) decodePatternRune(s string, escaped bool) (rune, int) {
+	if escaped && g.byteSemantics {
+		if b, ok := decodeHexEscape(s); ok {
+			return rune(b), 3
+		}
+	}
+	return g.decodeRune(s)
+}
+
+// Returns true if the runes `a` and `b` are equal, taking WithCaseInsensitive
+// and WithByteSemantics into account.
+func (g *glob) matchRune(a, b rune) bool {
+	if g.byteSemantics && g.caseInsensitive {
+		// bytes are not runes, so we can only fold ASCII
+		return a == b || (a < utf8.RuneSelf && b < utf8.RuneSelf && matchRune(a, b, true))
+	}
+	return matchRune(a, b, g.caseInsensitive)
+}
+
+// Returns true if `r` is in the range `lo` to `hi`, taking
+// WithCaseInsensitive and WithByteSemantics into account.
+func (g *glob) matchRange(lo, hi, r rune) bool {
+	if g.byteSemantics && r >= utf8.RuneSelf {
+		return lo <= r && r <= hi
+	}
+	return matchRange(lo, hi, r, g.caseInsensitive)
+}
+
 func matchRune(a, b rune, caseInsensitive bool) bool {
+	if a == b {
+		return true
+	}
 	if caseInsensitive {
-		return unicode.ToLower(a) == unicode.ToLower(b)
+		// walk the case folding orbit of `a`, which contains every rune that is
+		// equivalent to `a` under Unicode simple case folding
+		for r := unicode.SimpleFold(a); r != a; r = unicode.SimpleFold(r) {
+			if r == b {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Returns true if `r`, or, if caseInsensitive, any rune equivalent to `r`
+// under Unicode simple case folding, is in the range `lo` to `hi`.
+func matchRange(lo, hi, r rune, caseInsensitive bool) bool {
+	if lo <= r && r <= hi {
+		return true
+	}
+	if caseInsensitive {
+		for f := unicode.SimpleFold(r); f != r; f = unicode.SimpleFold(f) {
+			if lo <= f && f <= hi {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// If s begins with `xNN`, where NN are two hexadecimal digits, returns the
+// byte represented by NN and true.
+func decodeHexEscape(s string) (byte, bool) {
+	if len(s) < 3 || s[0] != 'x' {
+		return 0, false
+	}
+	hi, ok := unhex(s[1])
+	if !ok {
+		return 0, false
+	}
+	lo, ok := unhex(s[2])
+	if !ok {
+		return 0, false
+	}
+	return hi<<4 | lo, true
+}
+
+func unhex(c byte) (byte, bool) {
+	switch {
+	case '0' <= c && c <= '9':
+		return c - '0', true
+	case 'a' <= c && c <= 'f':
+		return c - 'a' + 10, true
+	case 'A' <= c && c <= 'F':
+		return c - 'A' + 10, true
+	}
+	return 0, false
+}
+
+// Returns false if `name` has a hidden segment (one that starts with a dot)
+// beginning at `nameIdx` that the pattern cannot match because the pattern
+// segment at `patIdx` does not start with an explicit dot. Always returns true
+// if WithSkipHidden is not enabled.
+func (g *glob) canMatchHidden(pattern, name string, patIdx, nameIdx int, separator rune) bool {
+	if !g.skipHidden || name[nameIdx] != '.' || !isSegmentStart(name, nameIdx, separator) {
+		return true
+	}
+	if !isSegmentStart(pattern, patIdx, separator) {
+		return false
+	}
+
+	switch pattern[patIdx] {
+	case '.', '{':
+		// alts will be expanded and checked again
+		return true
+	case '*':
+		// `**` may match zero directories; it is not allowed to consume the hidden
+		// segment itself, which is checked while backtracking
+		if patIdx+1 < len(pattern) && pattern[patIdx+1] == '*' {
+			if patIdx+2 == len(pattern) {
+				return true
+			}
+			r, _ := utf8.DecodeRuneInString(pattern[patIdx+2:])
+			return r == separator
+		}
+	case '\\':
+		return separator != '\\' && patIdx+1 < len(pattern) && pattern[patIdx+1] == '.'
+	}
+	return false
+}
+
+// Returns true if idx is at the start of a path segment in s
+func isSegmentStart(s string, idx int, separator rune) bool {
+	if idx == 0 {
+		return true
+	}
+	r, _ := utf8.DecodeLastRuneInString(s[:idx])
+	return r == separator
+}
+
+// Returns true if any path segment in s begins with a dot
+func hasHiddenSegment(s string, separator rune) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] == '.' && isSegmentStart(s, i, separator) {
+			return true
+		}
 	}
-	return a == b
+	return false
 }
 
 func isZeroLengthPattern(pattern string, separator rune, validate bool) (ret bool, err error) {
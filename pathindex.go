@@ -0,0 +1,235 @@
+package doublestar
+
+import (
+	"errors"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// PathIndex is an in-memory tree of slash-separated paths, such as the files
+// in a workspace, that can be globbed over and over without touching a file
+// system. Paths are organized as a trie, so, just like Glob on a real file
+// system, a query only visits the directories that could contain a match.
+// Every intermediate segment of a path is an implicit directory: after adding
+// `a/b/c.go`, `a` and `a/b` are directories.
+//
+// Paths can be added and removed at any time. A PathIndex is safe for
+// concurrent use by multiple goroutines.
+type PathIndex struct {
+	mu   sync.RWMutex
+	root indexNode
+}
+
+type indexNode struct {
+	name string
+
+	// sorted by name
+	children []*indexNode
+
+	// set if the path was added as a file or, with a trailing slash, as a
+	// directory; a node that has neither and no children is removed
+	file bool
+	dir  bool
+}
+
+func (n *indexNode) isDir() bool {
+	return n.dir || len(n.children) > 0
+}
+
+// Returns the index of the child called `name`, or where it would be inserted
+func (n *indexNode) search(name string) int {
+	return sort.Search(len(n.children), func(i int) bool { return n.children[i].name >= name })
+}
+
+// Returns the child called `name`, or nil. If `caseInsensitive` is set and
+// there is no exact match, the first child whose name is equal under simple
+// case folding is returned.
+func (n *indexNode) child(name string, caseInsensitive bool) *indexNode {
+	if i := n.search(name); i < len(n.children) && n.children[i].name == name {
+		return n.children[i]
+	}
+	if caseInsensitive {
+		for _, c := range n.children {
+			if strings.EqualFold(c.name, name) {
+				return c
+			}
+		}
+	}
+	return nil
+}
+
+// NewPathIndex returns a PathIndex containing `paths`. See Add.
+func NewPathIndex(paths []string) *PathIndex {
+	idx := &PathIndex{}
+	for _, p := range paths {
+		idx.add(p)
+	}
+	return idx
+}
+
+// Add adds `p` to the index, along with its parent directories. `p` must be
+// a slash-separated path relative to the root of the index, such as
+// `a/b/c.go`; it is cleaned with path.Clean, and ignored if it is still not a
+// valid path, as defined by fs.ValidPath, or if it is the root itself. If `p`
+// ends in a slash, it is added as a directory, otherwise, as a file. Adding a
+// path that is already in the index does nothing.
+func (idx *PathIndex) Add(p string) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	idx.add(p)
+}
+
+func (idx *PathIndex) add(p string) {
+	isDir := strings.HasSuffix(p, "/")
+	p = path.Clean(p)
+	if p == "." || !fs.ValidPath(p) {
+		return
+	}
+
+	n := &idx.root
+	for _, name := range strings.Split(p, "/") {
+		i := n.search(name)
+		if i == len(n.children) || n.children[i].name != name {
+			n.children = append(n.children, nil)
+			copy(n.children[i+1:], n.children[i:])
+			n.children[i] = &indexNode{name: name}
+		}
+		n = n.children[i]
+	}
+	if isDir {
+		n.dir = true
+	} else {
+		n.file = true
+	}
+}
+
+// Remove removes `p` from the index, along with everything under it if it is
+// a directory. Parent directories that no longer contain anything are
+// removed, too, unless they were added as directories. Returns false if `p`
+// was not in the index.
+func (idx *PathIndex) Remove(p string) bool {
+	p = path.Clean(p)
+	if p == "." || !fs.ValidPath(p) {
+		return false
+	}
+
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	names := strings.Split(p, "/")
+	parents := make([]*indexNode, 0, len(names))
+	n := &idx.root
+	for _, name := range names {
+		parents = append(parents, n)
+		if n = n.child(name, false); n == nil {
+			return false
+		}
+	}
+
+	// detach the node, then any parents that are now empty
+	for i := len(parents) - 1; i >= 0; i-- {
+		parent := parents[i]
+		j := parent.search(names[i])
+		parent.children = append(parent.children[:j], parent.children[j+1:]...)
+		if i == 0 || len(parent.children) > 0 || parent.file || parent.dir {
+			break
+		}
+	}
+	return true
+}
+
+// Glob returns the paths in the index that match `pattern`, just like Glob
+// would if the paths were files on a file system. All of the same options may
+// be passed. If WithCaseInsensitive is passed, the index behaves like a case
+// insensitive file system: segments of `pattern` without meta characters
+// match the first path segment that is equal under simple case folding, and
+// the match is returned spelled like the pattern.
+func (idx *PathIndex) Glob(pattern string, opts ...GlobOption) ([]string, error) {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+	return Glob(idx.fs(opts), pattern, opts...)
+}
+
+// GlobWalk calls `fn` for every path in the index that matches `pattern`,
+// just like GlobWalk. The index cannot be modified until GlobWalk returns, so
+// `fn` must not call Add or Remove.
+func (idx *PathIndex) GlobWalk(pattern string, fn GlobWalkFunc, opts ...GlobOption) error {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+	return GlobWalk(idx.fs(opts), pattern, fn, opts...)
+}
+
+func (idx *PathIndex) fs(opts []GlobOption) indexFS {
+	return indexFS{root: &idx.root, caseInsensitive: newGlob(opts...).caseInsensitive}
+}
+
+// indexFS is a read-only view of a PathIndex as an fs.FS with ReadDir and
+// Stat, which is all that globbing needs. The caller must hold the read lock.
+type indexFS struct {
+	root            *indexNode
+	caseInsensitive bool
+}
+
+var errIndexOpen = errors.New("a PathIndex cannot be opened")
+
+func (f indexFS) Open(name string) (fs.File, error) {
+	return nil, &fs.PathError{Op: "open", Path: name, Err: errIndexOpen}
+}
+
+func (f indexFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	n := f.lookup(name)
+	if n == nil {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: fs.ErrNotExist}
+	}
+	if !n.isDir() {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: errNotDir}
+	}
+
+	entries := make([]fs.DirEntry, len(n.children))
+	for i, c := range n.children {
+		entries[i] = indexEntry(c)
+	}
+	return entries, nil
+}
+
+var errNotDir = errors.New("not a directory")
+
+func (f indexFS) Stat(name string) (fs.FileInfo, error) {
+	n := f.lookup(name)
+	if n == nil {
+		return nil, &fs.PathError{Op: "stat", Path: name, Err: fs.ErrNotExist}
+	}
+	if n == f.root {
+		return listerEntry{Entry{Name: ".", Type: fs.ModeDir}}, nil
+	}
+	return indexEntry(n), nil
+}
+
+func (f indexFS) lookup(name string) *indexNode {
+	if name == "." {
+		return f.root
+	}
+	if !fs.ValidPath(name) {
+		return nil
+	}
+	n := f.root
+	for _, segment := range strings.Split(name, "/") {
+		if n = n.child(segment, f.caseInsensitive); n == nil {
+			return nil
+		}
+	}
+	return n
+}
+
+// Returns an immutable fs.DirEntry for the node, so that callers can hold on
+// to it after the index changes
+func indexEntry(n *indexNode) listerEntry {
+	var typ fs.FileMode
+	if n.isDir() {
+		typ = fs.ModeDir
+	}
+	return listerEntry{Entry{Name: n.name, Type: typ}}
+}
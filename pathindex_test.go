@@ -0,0 +1,176 @@
+package doublestar
+
+import (
+	"io/fs"
+	"sort"
+	"strconv"
+	"sync"
+	"testing"
+	"testing/fstest"
+)
+
+var pathIndexTestPaths = []string{
+	".git/config",
+	"README.md",
+	"cmd/tool/main.go",
+	"cmd/tool/main_test.go",
+	"docs/",
+	"internal/a/a.go",
+	"internal/a/b/b.go",
+	"internal/c.go",
+	"main.go",
+}
+
+// PathIndex must return the same matches as Glob and GlobWalk on a file
+// system with the same files.
+func TestPathIndexAgreesWithGlob(t *testing.T) {
+	fsys := fstest.MapFS{}
+	for _, p := range pathIndexTestPaths {
+		if p[len(p)-1] == '/' {
+			fsys[p[:len(p)-1]] = &fstest.MapFile{Mode: fs.ModeDir | 0755}
+		} else {
+			fsys[p] = &fstest.MapFile{}
+		}
+	}
+	idx := NewPathIndex(pathIndexTestPaths)
+
+	patterns := []string{
+		"**", "**/", "*", "*/", "**/*.go", "**/*_test.go", "cmd/**", "cmd/*/", "internal/*",
+		"main.go", "docs", "docs/", "{cmd,internal}/**/*.go", "internal/[ab]/**", "nope/*",
+		"MAIN.go", "Internal/*.GO", "cmd/tool/main.go/x",
+	}
+	optss := [][]GlobOption{
+		nil,
+		{WithSkipHidden()},
+		{WithFilesOnly()},
+		{WithDirsOnly()},
+		{WithBreadthFirstTraversal()},
+		{WithSortOrder(SortLexical)},
+	}
+	for _, opts := range optss {
+		for _, pattern := range patterns {
+			expected, err := Glob(fsys, pattern, opts...)
+			if err != nil {
+				t.Fatalf("Glob(%#q) error: %v", pattern, err)
+			}
+
+			matches, err := idx.Glob(pattern, opts...)
+			if err != nil || !equalSlices(matches, expected) {
+				t.Errorf("PathIndex.Glob(%#q, %#v) = %v, %v want %v", pattern, newGlob(opts...), matches, err, expected)
+			}
+
+			var walked []string
+			err = idx.GlobWalk(pattern, func(p string, d fs.DirEntry) error {
+				walked = append(walked, p)
+				return nil
+			}, opts...)
+			if err != nil || !compareSlices(walked, expected) {
+				t.Errorf("PathIndex.GlobWalk(%#q, %#v) = %v, %v want %v", pattern, newGlob(opts...), walked, err, expected)
+			}
+		}
+	}
+
+	if _, err := idx.Glob("a/["); err != ErrBadPattern {
+		t.Errorf("PathIndex.Glob(`a/[`) error = %v want ErrBadPattern", err)
+	}
+}
+
+func TestPathIndexCaseInsensitive(t *testing.T) {
+	idx := NewPathIndex(pathIndexTestPaths)
+	tests := []struct {
+		pattern  string
+		expected []string
+	}{
+		{"MAIN.go", []string{"MAIN.go"}},
+		{"Internal/*.GO", []string{"Internal/c.go"}},
+		{"CMD/**/*_TEST.go", []string{"CMD/tool/main_test.go"}},
+		{"readme.*", []string{"README.md"}},
+	}
+
+	for idx2, tt := range tests {
+		matches, err := idx.Glob(tt.pattern, WithCaseInsensitive())
+		if err != nil || !compareSlices(matches, tt.expected) {
+			t.Errorf("#%v. PathIndex.Glob(%#q, WithCaseInsensitive()) = %v, %v want %v", idx2, tt.pattern, matches, err, tt.expected)
+		}
+	}
+}
+
+func TestPathIndexAddRemove(t *testing.T) {
+	idx := NewPathIndex(nil)
+	glob := func(pattern string) []string {
+		matches, err := idx.Glob(pattern)
+		if err != nil {
+			t.Fatalf("PathIndex.Glob(%#q) error: %v", pattern, err)
+		}
+		sort.Strings(matches)
+		return matches
+	}
+
+	idx.Add("a/b/c.go")
+	idx.Add("./a/d.go")
+	idx.Add("a/b/c.go")
+	idx.Add("e/")
+	idx.Add("../x.go")
+	idx.Add("/y.go")
+	idx.Add(".")
+	if matches := glob("**"); !equalSlices(matches, []string{".", "a", "a/b", "a/b/c.go", "a/d.go", "e"}) {
+		t.Errorf("PathIndex.Glob(`**`) after Add = %v", matches)
+	}
+
+	if !idx.Remove("a/b/c.go") {
+		t.Errorf("PathIndex.Remove(`a/b/c.go`) = false want true")
+	}
+	if idx.Remove("a/b/c.go") {
+		t.Errorf("PathIndex.Remove(`a/b/c.go`) twice = true want false")
+	}
+	// `a/b` is removed because it is empty, but `a` is not
+	if matches := glob("**"); !equalSlices(matches, []string{".", "a", "a/d.go", "e"}) {
+		t.Errorf("PathIndex.Glob(`**`) after Remove(`a/b/c.go`) = %v", matches)
+	}
+
+	// removing a directory removes everything under it
+	idx.Add("a/b/c.go")
+	idx.Add("e/f/g.go")
+	idx.Remove("a")
+	idx.Remove("e/f/g.go")
+	if matches := glob("**"); !equalSlices(matches, []string{".", "e"}) {
+		t.Errorf("PathIndex.Glob(`**`) after Remove(`a`) = %v", matches)
+	}
+	if idx.Remove(".") || idx.Remove("nope") {
+		t.Errorf("PathIndex.Remove() of a path not in the index = true want false")
+	}
+}
+
+func TestPathIndexConcurrent(t *testing.T) {
+	idx := NewPathIndex(pathIndexTestPaths)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(2)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				p := "gen/" + strconv.Itoa(i) + "/" + strconv.Itoa(j) + ".go"
+				idx.Add(p)
+				if j%2 == 0 {
+					idx.Remove(p)
+				}
+			}
+		}(i)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				if _, err := idx.Glob("**/*.go"); err != nil {
+					t.Errorf("PathIndex.Glob(`**/*.go`) error: %v", err)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	matches, _ := idx.Glob("gen/**/*.go")
+	if len(matches) != 200 {
+		t.Errorf("PathIndex.Glob(`gen/**/*.go`) found %v matches want 200", len(matches))
+	}
+}
@@ -0,0 +1,107 @@
+package doublestar
+
+import (
+	"sort"
+	"strings"
+)
+
+// GlobPaths is like Glob, but matches `pattern` against a flat list of
+// slash-separated paths instead of an fs.FS, such as the keys in object
+// storage, which have no directory entries. Every intermediate segment of a
+// path is treated as an implicit directory, so `logs/*` returns `logs/2024` if
+// `paths` contains `logs/2024/01/a.gz`, just like Glob would on a real file
+// system. A path that ends in a slash, such as `logs/2024/`, only marks a
+// directory. The root directory, `.`, is only returned by the patterns `.` and
+// `**`, just like Glob.
+//
+// `paths` must be sorted, as by sort.Strings. GlobPaths uses a binary search
+// to find the paths that start with the literal prefix of `pattern` (the part
+// before the first meta character), and only matches those, so globbing a
+// small part of a large list is cheap. Unless WithCaseInsensitive or
+// WithUnicodeNormalization is passed, in which case a path may match without
+// starting with the literal prefix, and every path is matched. Unlike Glob on
+// a case sensitive file system, WithCaseInsensitive applies to every segment
+// of the pattern, even ones without meta characters.
+//
+// The matches are sorted and contain no duplicates. Options that affect how
+// paths are matched, such as WithCaseInsensitive, WithSkipHidden,
+// WithFilesOnly, WithDirsOnly, and WithLimit, behave as they do for Glob;
+// options that only concern reading a file system are ignored. GlobPaths
+// returns ErrBadPattern if the pattern is malformed.
+func GlobPaths(paths []string, pattern string, opts ...GlobOption) ([]string, error) {
+	g := newGlob(opts...)
+	if !g.validatePattern(pattern, '/') {
+		return nil, ErrBadPattern
+	}
+	pattern = g.normalize(pattern)
+
+	dirPattern := false
+	if trimmed, ok := trimTrailingSeparator(pattern, '/', true); ok {
+		pattern = trimmed
+		dirPattern = true
+	}
+	literal := indexMeta(pattern) == -1
+
+	// only paths that start with the literal prefix can match
+	prefix := ""
+	if !g.caseInsensitive && g.normalizer == nil {
+		if idx := indexMeta(pattern); idx == -1 {
+			prefix = g.unescapeMeta(pattern)
+		} else {
+			prefix = g.unescapeMeta(pattern[:idx])
+		}
+		if idx := strings.IndexByte(prefix, '\\'); idx != -1 {
+			// escapes that unescapeMeta doesn't handle, such as an escaped slash
+			prefix = prefix[:idx]
+		}
+	}
+	lo := sort.SearchStrings(paths, prefix)
+	hi := lo + sort.Search(len(paths)-lo, func(i int) bool {
+		return !strings.HasPrefix(paths[lo+i], prefix)
+	})
+
+	// candidates maps each path and implicit directory to whether it is a
+	// directory
+	candidates := make(map[string]bool)
+	if pattern == "**" || pattern == "." {
+		candidates["."] = true
+	}
+	for _, p := range paths[lo:hi] {
+		isDir := false
+		if trimmed, ok := trimTrailingSeparator(p, '/', false); ok {
+			p = trimmed
+			isDir = true
+		}
+		// the prefix may end in a slash: `logs/**` can match `logs`
+		for i := len(prefix) - 1; i < len(p); i++ {
+			if i > 0 && p[i] == '/' {
+				candidates[p[:i]] = true
+			}
+		}
+		if p != "" && !candidates[p] {
+			candidates[p] = isDir
+		}
+	}
+
+	var matches []string
+	for p, isDir := range candidates {
+		if (dirPattern && !isDir) || !g.matchesFileType(isDir) {
+			continue
+		}
+		if p != "." || pattern == "." {
+			if matched, _ := g.matchWithSeparator(pattern, g.normalize(p), '/', false); !matched {
+				continue
+			}
+		}
+		if literal && dirPattern {
+			// just like Glob, a literal pattern's trailing slash is kept
+			p += "/"
+		}
+		matches = append(matches, p)
+	}
+	sort.Strings(matches)
+	if g.limit > 0 && len(matches) > g.limit {
+		matches = matches[:g.limit]
+	}
+	return matches, nil
+}
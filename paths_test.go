@@ -0,0 +1,105 @@
+package doublestar
+
+import (
+	"sort"
+	"testing"
+	"testing/fstest"
+)
+
+var globPathsTestKeys = []string{
+	".hidden/a.gz",
+	"Logs/2024/01/c.gz",
+	"logs/2023/12/z.gz",
+	"logs/2024/01/a.gz",
+	"logs/2024/01/b.txt",
+	"logs/2024/02/a.gz",
+	"logs/2024x",
+	"logs/x",
+	"logs0",
+	"top.gz",
+}
+
+func TestGlobPaths(t *testing.T) {
+	tests := []struct {
+		pattern  string
+		expected []string
+		opts     []GlobOption
+	}{
+		{"logs/*", []string{"logs/2023", "logs/2024", "logs/2024x", "logs/x"}, nil},
+		{"logs/*/", []string{"logs/2023", "logs/2024"}, nil},
+		{"logs/202*/*/*.gz", []string{"logs/2023/12/z.gz", "logs/2024/01/a.gz", "logs/2024/02/a.gz"}, nil},
+		{"logs/2024", []string{"logs/2024"}, nil},
+		{"logs/2024/", []string{"logs/2024/"}, nil},
+		{"logs/x/", nil, nil},
+		{"logs/nope", nil, nil},
+		{"logs/**/a.gz", []string{"logs/2024/01/a.gz", "logs/2024/02/a.gz"}, nil},
+		{"logs/**/", []string{"logs", "logs/2023", "logs/2023/12", "logs/2024", "logs/2024/01", "logs/2024/02"}, nil},
+		{"{logs,Logs}/2024/01/*", []string{"Logs/2024/01/c.gz", "logs/2024/01/a.gz", "logs/2024/01/b.txt"}, nil},
+		{"*", []string{".hidden", "Logs", "logs", "logs0", "top.gz"}, nil},
+		{"*", []string{"Logs", "logs", "logs0", "top.gz"}, []GlobOption{WithSkipHidden()}},
+		{"*", []string{"logs0", "top.gz"}, []GlobOption{WithFilesOnly()}},
+		{"*", []string{".hidden", "Logs", "logs"}, []GlobOption{WithDirsOnly()}},
+		{"**/*.gz", []string{"Logs/2024/01/c.gz", "logs/2023/12/z.gz", "logs/2024/01/a.gz", "logs/2024/02/a.gz", "top.gz"}, []GlobOption{WithSkipHidden()}},
+		{"logs/2024/01/*", []string{"Logs/2024/01/c.gz", "logs/2024/01/a.gz", "logs/2024/01/b.txt"}, []GlobOption{WithCaseInsensitive()}},
+		{"logs/**", []string{"logs", "logs/2023", "logs/2023/12"}, []GlobOption{WithLimit(3)}},
+		{".", []string{"."}, nil},
+		{"logs/[", nil, nil},
+	}
+
+	for idx, tt := range tests {
+		matches, err := GlobPaths(globPathsTestKeys, tt.pattern, tt.opts...)
+		expectedErr := error(nil)
+		if !ValidatePattern(tt.pattern) {
+			expectedErr = ErrBadPattern
+		}
+		if err != expectedErr {
+			t.Errorf("#%v. GlobPaths(%#q) has error %v want %v", idx, tt.pattern, err, expectedErr)
+		}
+		if !equalSlices(matches, tt.expected) {
+			t.Errorf("#%v. GlobPaths(%#q) = %v want %v", idx, tt.pattern, matches, tt.expected)
+		}
+	}
+}
+
+func TestGlobPathsDirMarkers(t *testing.T) {
+	keys := []string{"a/", "a/b/", "a/b/c.txt", "d/"}
+	matches, err := GlobPaths(keys, "*/", WithDirsOnly())
+	if err != nil || !equalSlices(matches, []string{"a", "d"}) {
+		t.Errorf("GlobPaths(`*/`) = %v, %v want [a d]", matches, err)
+	}
+	if matches, _ = GlobPaths(keys, "**", WithFilesOnly()); !equalSlices(matches, []string{"a/b/c.txt"}) {
+		t.Errorf("GlobPaths(`**`, WithFilesOnly()) = %v want [a/b/c.txt]", matches)
+	}
+}
+
+// GlobPaths must return the same matches as Glob on a file system with the
+// same files.
+func TestGlobPathsAgreesWithGlob(t *testing.T) {
+	fsys := fstest.MapFS{}
+	for _, key := range globPathsTestKeys {
+		fsys[key] = &fstest.MapFile{}
+	}
+
+	patterns := []string{
+		"**", "**/", "*", "*/", "**/*.gz", "logs/**", "logs/*", "logs/*/", "logs/2024",
+		"logs/2024/", "logs/202?/0[1-2]/*", "{logs,top.gz}", "logs/{2023,2024}/**/*.gz",
+		"*/2024/**", "logs*", "logs/**/", ".", "nope/*",
+	}
+	// WithCaseInsensitive is left out: on a case sensitive file system, Glob
+	// Stats segments without meta characters, so they match case sensitively
+	optss := [][]GlobOption{nil, {WithSkipHidden()}, {WithFilesOnly()}, {WithDirsOnly()}}
+	for _, opts := range optss {
+		for _, pattern := range patterns {
+			expected, err := Glob(fsys, pattern, opts...)
+			if err != nil {
+				t.Fatalf("Glob(%#q) error: %v", pattern, err)
+			}
+			sort.Strings(expected)
+
+			matches, err := GlobPaths(globPathsTestKeys, pattern, opts...)
+			if err != nil || !equalSlices(matches, expected) {
+				t.Errorf("GlobPaths(%#q, %#v) = %v, %v want %v", pattern, newGlob(opts...), matches, err, expected)
+			}
+		}
+	}
+}
@@ -0,0 +1,154 @@
+package doublestar
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// CouldMatchPrefix returns true if some path inside the directory `dir` could
+// match `pattern`. This is the same decision Glob and GlobWalk make when they
+// decide whether or not to read a directory, so walkers that do not use an
+// fs.FS, such as ones listing keys in object storage, can prune directories
+// just like Glob does. For example, nothing under `x` can match `a/**/c/*.go`,
+// but something under `a/b` might:
+//
+//	doublestar.CouldMatchPrefix("a/**/c/*.go", "a/b") // true
+//	doublestar.CouldMatchPrefix("a/**/c/*.go", "x")   // false
+//
+// `dir` itself is not considered: CouldMatchPrefix("a/*.go", "a/b") returns
+// false, even though "a/b" could be a directory named `b`, since nothing
+// inside of it can match. Pass "." or "" as `dir` to ask about the root. A
+// true result does not mean that anything will match, only that it might.
+//
+// CouldMatchPrefix accepts the same options as MatchWithOptions, and uses `/`
+// as the path separator unless WithSeparator is passed. If WithSkipHidden is
+// passed, `**` will not traverse hidden directories, just like Glob. If the
+// pattern is malformed, ErrBadPattern is returned.
+func CouldMatchPrefix(pattern, dir string, opts ...MatchOption) (bool, error) {
+	g := newGlob(opts...)
+	separator := g.separatorOrDefault('/')
+	if !isValidSeparator(separator) {
+		return false, ErrBadPattern
+	}
+	pattern = g.normalize(pattern)
+	if !g.validatePattern(pattern, separator) {
+		return false, ErrBadPattern
+	}
+
+	dirSegments := splitDirSegments(g.normalize(dir), separator)
+	return g.couldMatchSegments(pattern, dirSegments, separator), nil
+}
+
+// prefixState is the state of couldMatchSegments: what is left of the
+// pattern, and the index of the next dir segment
+type prefixState struct {
+	pattern string
+	di      int
+}
+
+// Returns true if `pattern` could match a path that has the dir segments as a
+// proper prefix. Segments are matched one at a time with matchWithSeparator,
+// which handles alts itself. Only alts that contain a separator or `**` have
+// to be expanded, and only once everything before them has matched, so
+// patterns with many alts are not expanded into every combination.
+func (g *glob) couldMatchSegments(pattern string, dir []string, separator rune) bool {
+	allowEscaping := separator != '\\'
+
+	// without memoization, several `**` could take exponential time
+	memo := make(map[prefixState]bool)
+	var match func(pattern string, di int) bool
+	match = func(pattern string, di int) bool {
+		if di == len(dir) {
+			// whatever is left of the pattern must match something inside dir
+			return pattern != ""
+		}
+		if pattern == "" {
+			return false
+		}
+
+		state := prefixState{pattern, di}
+		if matched, ok := memo[state]; ok {
+			return matched
+		}
+
+		var matched bool
+		segment, rest, openingIdx, closingIdx := nextPatternSegment(pattern, separator, allowEscaping)
+		switch {
+		case openingIdx != -1:
+			// try each branch of the alt in place of the alt
+			prefix, suffix := pattern[:openingIdx], pattern[closingIdx+1:]
+			for startIdx := openingIdx + 1; !matched && startIdx <= closingIdx; {
+				nextIdx := indexNextAlt(pattern[startIdx:closingIdx], allowEscaping)
+				if nextIdx == -1 {
+					nextIdx = closingIdx
+				} else {
+					nextIdx += startIdx
+				}
+				matched = match(prefix+pattern[startIdx:nextIdx]+suffix, di)
+				startIdx = nextIdx + 1
+			}
+
+		case segment == "**":
+			// `**` matches zero dirs, or this dir and maybe more
+			matched = match(rest, di) || (!g.isHidden(dir[di]) && match(pattern, di+1))
+
+		default:
+			matched, _ = g.matchWithSeparator(segment, dir[di], separator, false)
+			matched = matched && match(rest, di+1)
+		}
+
+		memo[state] = matched
+		return matched
+	}
+	return match(pattern, 0)
+}
+
+// Splits `dir` into segments. The root, "." or "", has no segments, while
+// an absolute path starts with an empty segment.
+func splitDirSegments(dir string, separator rune) []string {
+	if dir == "" || dir == "." {
+		return nil
+	}
+	if trimmed, ok := trimTrailingSeparator(dir, separator, false); ok {
+		dir = trimmed
+	}
+	if dir == string(separator) {
+		return []string{""}
+	}
+	return strings.Split(dir, string(separator))
+}
+
+// Returns the first segment of `pattern` and the rest of the pattern after
+// the separator. An escaped separator still matches the separator, so it ends
+// the segment, too. If the segment has an alt that contains a separator or
+// `**`, and so cannot be matched against one segment, the indexes of its
+// braces are returned instead, and the segment must be expanded. Otherwise,
+// openingIdx and closingIdx are -1. `pattern` must be valid.
+func nextPatternSegment(pattern string, separator rune, allowEscaping bool) (segment, rest string, openingIdx, closingIdx int) {
+	for i := 0; i < len(pattern); {
+		if allowEscaping && pattern[i] == '\\' && i+1 < len(pattern) {
+			r, size := utf8.DecodeRuneInString(pattern[i+1:])
+			if r == separator {
+				return pattern[:i], pattern[i+1+size:], -1, -1
+			}
+			i += 1 + size
+			continue
+		}
+		if pattern[i] == '{' {
+			if idx := indexMatchedClosingAlt(pattern[i+1:], allowEscaping); idx != -1 {
+				alt := pattern[i+1 : i+1+idx]
+				if strings.ContainsRune(alt, separator) || strings.Contains(alt, "**") {
+					return "", "", i, i + 1 + idx
+				}
+				i += idx + 2
+				continue
+			}
+		}
+		r, size := utf8.DecodeRuneInString(pattern[i:])
+		if r == separator {
+			return pattern[:i], pattern[i+size:], -1, -1
+		}
+		i += size
+	}
+	return pattern, "", -1, -1
+}
@@ -0,0 +1,168 @@
+package doublestar
+
+import (
+	"io/fs"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+type CouldMatchPrefixTest struct {
+	pattern  string
+	dir      string
+	expected bool
+	opts     []MatchOption
+}
+
+var couldMatchPrefixTests = []CouldMatchPrefixTest{
+	{"a/**/c/*.go", "a/b", true, nil},
+	{"a/**/c/*.go", "a", true, nil},
+	{"a/**/c/*.go", ".", true, nil},
+	{"a/**/c/*.go", "", true, nil},
+	{"a/**/c/*.go", "x", false, nil},
+	{"a/**/c/*.go", "a/b/c", true, nil},
+	{"a/**/c/*.go", "a/b/d/e", true, nil},
+	{"a/*.go", "a", true, nil},
+	{"a/*.go", "a/", true, nil},
+	{"a/*.go", "a/b", false, nil},
+	{"a/*.go", "b", false, nil},
+	{"a/b", "a/b", false, nil},
+	{"a/b/", "a", true, nil},
+	{"a/b/", "a/b", false, nil},
+	{"a/**", "a", true, nil},
+	{"a/**", "a/b/c", true, nil},
+	{"a/**", "b", false, nil},
+	{"**", "a/b/c", true, nil},
+	{"**/*.go", "a/b", true, nil},
+	{"*/b/*", "x/b", true, nil},
+	{"*/b/*", "x/c", false, nil},
+	{"a?/b", "ax", true, nil},
+	{"a[xy]/b", "az", false, nil},
+	{"{a,b}/c/*", "b/c", true, nil},
+	{"{a,b}/c/*", "c", false, nil},
+	{"{a/b,c}/*", "a/b", true, nil},
+	{"{a/b,c}/*", "a/c", false, nil},
+	{"x{a,b{c,d}}/*", "xbd", true, nil},
+	{"x{a,b{c,d}}/*", "xb", false, nil},
+	{"x{a,}/*", "x", true, nil},
+	{"a\\/b/*", "a/b", true, nil},
+	{"a\\*/*", "a*", true, nil},
+	{"a\\*/*", "ab", false, nil},
+	{"/a/*", "/a", true, nil},
+	{"/a/*", "/", true, nil},
+	{"/a/*", "/b", false, nil},
+	{"**/*.go", ".git", true, nil},
+	{"**/*.go", ".git", false, []MatchOption{WithSkipHidden()}},
+	{"**/*.go", "a/.git/b", false, []MatchOption{WithSkipHidden()}},
+	{".git/**/*.go", ".git/b", true, []MatchOption{WithSkipHidden()}},
+	{"*/*.go", ".git", false, []MatchOption{WithSkipHidden()}},
+	{"A/*.go", "a", false, nil},
+	{"A/*.go", "a", true, []MatchOption{WithCaseInsensitive()}},
+	{"a.**.p99", "a.b", true, []MatchOption{WithSeparator('.')}},
+	{"a.*.p99", "a.b.c", false, []MatchOption{WithSeparator('.')}},
+	{"a/**/b/**/c/**/d", "a/x/y/z/w/v/u", true, nil},
+	{"a/{**,c}/b", "a/x/y", true, nil},
+	{"a/{c,d}/b", "a/x/y", false, nil},
+	{"{x,b/**}/c", "b/x/y", true, nil},
+	{"a{/b/**,c}/d", "a/b/x", true, nil},
+	{"a{/b/**,c}/d", "ac/x", false, nil},
+	{"a/[", "a", false, nil},
+	{"a/{b", "a", false, nil},
+}
+
+func TestCouldMatchPrefix(t *testing.T) {
+	for idx, tt := range couldMatchPrefixTests {
+		ok, err := CouldMatchPrefix(tt.pattern, tt.dir, tt.opts...)
+		if ok != tt.expected {
+			t.Errorf("#%v. CouldMatchPrefix(%#q, %#q) = %v want %v", idx, tt.pattern, tt.dir, ok, tt.expected)
+		}
+		expectedErr := error(nil)
+		if !ValidatePattern(tt.pattern) {
+			expectedErr = ErrBadPattern
+		}
+		if err != expectedErr {
+			t.Errorf("#%v. CouldMatchPrefix(%#q, %#q) has error %v want %v", idx, tt.pattern, tt.dir, err, expectedErr)
+		}
+	}
+
+	if _, err := CouldMatchPrefix("a/**b", "a", WithStrictDoubleStar()); err != ErrBadPattern {
+		t.Errorf("CouldMatchPrefix(`a/**b`, `a`, WithStrictDoubleStar()) error = %v want ErrBadPattern", err)
+	}
+}
+
+// Alts must not be expanded into every combination: each of these would be
+// 2^40 patterns.
+func TestCouldMatchPrefixManyAlts(t *testing.T) {
+	deep := strings.TrimSuffix(strings.Repeat("a/", 39), "/")
+	tests := []CouldMatchPrefixTest{
+		{strings.Repeat("{a,b}", 40) + "/c", "x", false, nil},
+		{strings.Repeat("{a,b}/", 40) + "c", deep, true, nil},
+		{strings.Repeat("{a,b}/", 40) + "c", deep + "/x", false, nil},
+		{strings.Repeat("{a/,b/}", 40) + "c", deep, true, nil},
+		{strings.Repeat("{a/,b/}", 40) + "c", deep + "/x", false, nil},
+		{strings.Repeat("{**/,b/}", 40) + "c", deep + "/x", true, nil},
+		{strings.Repeat("{**/,b/}", 40) + "c", ".git/" + deep, false, []MatchOption{WithSkipHidden()}},
+	}
+	for idx, tt := range tests {
+		ok, err := CouldMatchPrefix(tt.pattern, tt.dir, tt.opts...)
+		if ok != tt.expected || err != nil {
+			t.Errorf("#%v. CouldMatchPrefix(%#q, %#q) = %v, %v want %v", idx, tt.pattern, tt.dir, ok, err, tt.expected)
+		}
+	}
+}
+
+// Every directory that contains a match must be one that CouldMatchPrefix
+// says could contain one, and every directory that CouldMatchPrefix rules out
+// must be one that Glob never needed to read.
+func TestCouldMatchPrefixAgreesWithGlob(t *testing.T) {
+	fsys := fstest.MapFS{
+		"a/b/c/x.go":    {},
+		"a/b/c/y.txt":   {},
+		"a/b/d/x.go":    {},
+		"a/c/x.go":      {},
+		"a/.git/c/x.go": {},
+		"b/c/x.go":      {},
+		"b/x.go":        {},
+		"x.go":          {},
+	}
+	var dirs []string
+	fs.WalkDir(fsys, ".", func(p string, d fs.DirEntry, err error) error {
+		if d.IsDir() {
+			dirs = append(dirs, p)
+		}
+		return nil
+	})
+
+	patterns := []string{"a/**/c/*.go", "**/*.go", "*/c/*", "{a,b}/*/c/*.go", "a/b/*/x.go", "b/*.go", "x.go", "a/*/"}
+	for _, opts := range [][]GlobOption{nil, {WithSkipHidden()}} {
+		for _, pattern := range patterns {
+			counting := newCountingFS(fsys)
+			matches, err := Glob(counting, pattern, opts...)
+			if err != nil {
+				t.Fatalf("Glob(%#q) error: %v", pattern, err)
+			}
+
+			for _, dir := range dirs {
+				could, err := CouldMatchPrefix(pattern, dir, opts...)
+				if err != nil {
+					t.Fatalf("CouldMatchPrefix(%#q, %#q) error: %v", pattern, dir, err)
+				}
+				if could {
+					continue
+				}
+				prefix := dir + "/"
+				if dir == "." {
+					prefix = ""
+				}
+				for _, match := range matches {
+					if strings.HasPrefix(match, prefix) {
+						t.Errorf("CouldMatchPrefix(%#q, %#q) = false but Glob matched %#q", pattern, dir, match)
+					}
+				}
+				if counting.readDirs[dir] > 0 {
+					t.Errorf("CouldMatchPrefix(%#q, %#q) = false but Glob read it", pattern, dir)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,137 @@
+package doublestar
+
+import (
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// PatternRoot is returned by OpenPattern. It describes the file system a
+// pattern should be globbed against, and how to turn the matches back into
+// paths in the same form as the original pattern.
+type PatternRoot struct {
+	// FS is an os.DirFS rooted at Dir.
+	FS fs.FS
+
+	// Dir is the directory that FS is rooted at, using the system's path
+	// separator, with `~` expanded and `.` and `..` segments cleaned.
+	Dir string
+
+	// Pattern is the part of the original pattern that should be passed to
+	// Glob or GlobWalk, along with FS. It always uses `/` as the path
+	// separator.
+	Pattern string
+
+	// the base of the pattern, as the user wrote it (but cleaned)
+	prefix string
+}
+
+// OpenPattern takes a pattern as it might be given by a user on the command
+// line - absolute, relative with `..` segments, or starting with `~` - and
+// splits it into an fs.FS and a pattern that can be passed to Glob or
+// GlobWalk. io/fs does not allow paths that start with `/` or contain `.` or
+// `..` segments, so Glob(os.DirFS("."), "/etc/*") or Glob(os.DirFS("."),
+// "../*") silently return nothing. OpenPattern:
+//   - Runs `filepath.ToSlash()` on the pattern
+//   - Expands a leading `~` or `~/` to the user's home directory (`~user` is
+//     not supported)
+//   - Runs `SplitPattern()` to get a base path and a pattern
+//   - Cleans `.` and `..` segments in the base path
+//   - Creates an os.DirFS rooted at the base path
+//
+// Note that `.` and `..` segments after the first meta character are not
+// cleaned, because they are part of the pattern that will be passed to Glob.
+// Like Glob, such patterns will not match anything.
+//
+// Use the returned PatternRoot's Path method to convert matches back into
+// paths in the same form as the original pattern: absolute patterns result in
+// absolute paths, relative patterns result in relative paths, and patterns
+// starting with `~` result in paths starting with `~`. GlobRoot is a
+// convenience function that does all of this.
+//
+// OpenPattern does not check that the base path exists. Like FilepathGlob, it
+// is your responsibility to decide if the base path is "safe" in the context
+// of your application.
+func OpenPattern(pattern string) (*PatternRoot, error) {
+	pattern = filepath.ToSlash(pattern)
+
+	home := ""
+	if pattern == "~" || strings.HasPrefix(pattern, "~/") {
+		dir, err := os.UserHomeDir()
+		if err != nil {
+			return nil, err
+		}
+		home = filepath.ToSlash(dir)
+		pattern = strings.TrimLeft(pattern[1:], "/")
+	}
+
+	base, rel := SplitPattern(pattern)
+	if rel == "." || rel == ".." || (rel == "" && (base != "." || home != "")) {
+		// the pattern has no meta characters and names a directory: root the FS
+		// at that directory and glob for the directory itself
+		base = path.Join(base, rel)
+		rel = "."
+	}
+	base = path.Clean(base)
+
+	prefix, dir := base, base
+	if home != "" {
+		dir = path.Join(home, base)
+		if base == "." {
+			prefix = "~"
+		} else if base == ".." || strings.HasPrefix(base, "../") {
+			// the pattern escapes the home directory, so `~` can no longer be used
+			prefix = dir
+		} else {
+			prefix = "~/" + base
+		}
+	}
+
+	dir = filepath.FromSlash(dir)
+	return &PatternRoot{
+		FS:      os.DirFS(dir),
+		Dir:     dir,
+		Pattern: rel,
+		prefix:  prefix,
+	}, nil
+}
+
+// Path converts a match, returned by Glob or GlobWalk when called with the
+// PatternRoot's FS and Pattern, into a path in the same form as the pattern
+// that was passed to OpenPattern. The returned path uses the system's path
+// separator.
+func (r *PatternRoot) Path(match string) string {
+	return filepath.FromSlash(path.Join(r.prefix, match))
+}
+
+// GlobRoot returns the names of all files matching pattern or nil if there is
+// no matching file. Unlike Glob, pattern may be absolute, contain `.` and `..`
+// segments before its first meta character, or start with `~`. See
+// OpenPattern for details. Returned paths are in the same form as the pattern:
+// absolute patterns result in absolute paths, and relative patterns result in
+// relative paths. Returned paths use the system's path separator.
+//
+// GlobRoot ignores file system errors such as I/O errors reading directories
+// by default. Besides ErrBadPattern, GlobRoot may return an error if `~` was
+// used but the user's home directory could not be determined.
+func GlobRoot(pattern string, opts ...GlobOption) ([]string, error) {
+	root, err := OpenPattern(pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	matches, err := Glob(root.FS, root.Pattern, opts...)
+	ioErrs, partial := err.(*IOErrors)
+	if err != nil && !partial {
+		return nil, err
+	}
+	if partial {
+		ioErrs.rebase(root.Path)
+	}
+	for i := range matches {
+		matches[i] = root.Path(matches[i])
+	}
+	return matches, err
+}
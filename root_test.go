@@ -0,0 +1,100 @@
+package doublestar
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestOpenPattern(t *testing.T) {
+	tests := []struct {
+		pattern         string
+		dir, rel, match string
+		expected        string
+	}{
+		{"/tmp/*.txt", "/tmp", "*.txt", "a.txt", "/tmp/a.txt"},
+		{"/*", "/", "*", "tmp", "/tmp"},
+		{"/tmp/./x/../y/*", "/tmp/y", "*", "a", "/tmp/y/a"},
+		{"../../a/*/b", "../../a", "*/b", "c/b", "../../a/c/b"},
+		{"./a/../../b*", "..", "b*", "bc", "../bc"},
+		{"a/*/../b", "a", "*/../b", "x", "a/x"},
+		{`a\*b/*`, "a*b", "*", "c", "a*b/c"},
+		{"*.go", ".", "*.go", "a.go", "a.go"},
+		{"..", "..", ".", ".", ".."},
+		{"a/b/", "a/b", ".", ".", "a/b"},
+		{"/", "/", ".", ".", "/"},
+	}
+
+	for idx, tt := range tests {
+		root, err := OpenPattern(tt.pattern)
+		if err != nil {
+			t.Errorf("#%v. OpenPattern(%#q) error: %v", idx, tt.pattern, err)
+			continue
+		}
+		if root.Dir != filepath.FromSlash(tt.dir) || root.Pattern != tt.rel {
+			t.Errorf("#%v. OpenPattern(%#q) = %#q, %#q want %#q, %#q", idx, tt.pattern, root.Dir, root.Pattern, tt.dir, tt.rel)
+		}
+		if p := root.Path(tt.match); p != filepath.FromSlash(tt.expected) {
+			t.Errorf("#%v. OpenPattern(%#q).Path(%#q) = %#q want %#q", idx, tt.pattern, tt.match, p, tt.expected)
+		}
+	}
+}
+
+func TestGlobRoot(t *testing.T) {
+	tmp := t.TempDir()
+	mkdirp(tmp, "a")
+	mkdirp(tmp, "d")
+	touch(tmp, "a", "b.txt")
+	touch(tmp, "a", "c.txt")
+	touch(tmp, "d", "e.txt")
+
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = os.Chdir(filepath.Join(tmp, "d")); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+
+	home := os.Getenv("HOME")
+	os.Setenv("HOME", tmp)
+	defer os.Setenv("HOME", home)
+	if onWindows {
+		profile := os.Getenv("USERPROFILE")
+		os.Setenv("USERPROFILE", tmp)
+		defer os.Setenv("USERPROFILE", profile)
+	}
+
+	abs := func(parts ...string) string {
+		return filepath.Join(append([]string{tmp}, parts...)...)
+	}
+
+	tests := []struct {
+		pattern  string
+		expected []string
+	}{
+		{abs("a", "*.txt"), []string{abs("a", "b.txt"), abs("a", "c.txt")}},
+		{abs("d", "..", "a", "b.*"), []string{abs("a", "b.txt")}},
+		{abs() + "/*/../a/b.txt", nil},
+		{"../a/*.txt", []string{filepath.Join("..", "a", "b.txt"), filepath.Join("..", "a", "c.txt")}},
+		{"./../**/e.txt", []string{filepath.Join("..", "d", "e.txt")}},
+		{"*.txt", []string{"e.txt"}},
+		{"..", []string{".."}},
+		{"~/a/c.*", []string{filepath.Join("~", "a", "c.txt")}},
+		{"~", []string{"~"}},
+		{"~/../" + filepath.Base(tmp) + "/d/*", []string{abs("d", "e.txt")}},
+		{"../nope/*", nil},
+	}
+
+	for idx, tt := range tests {
+		matches, err := GlobRoot(tt.pattern)
+		if err != nil || !compareSlices(matches, tt.expected) {
+			t.Errorf("#%v. GlobRoot(%#q) = %#v, %v want %#v, nil", idx, tt.pattern, matches, err, tt.expected)
+		}
+	}
+
+	if _, err := GlobRoot("../a/["); err != ErrBadPattern {
+		t.Errorf("GlobRoot(`../a/[`) = %v want ErrBadPattern", err)
+	}
+}
@@ -0,0 +1,183 @@
+package doublestar
+
+import (
+	"errors"
+	"io/fs"
+	"sort"
+	"time"
+)
+
+// FileState is the state of a match recorded in a Snapshot.
+type FileState struct {
+	ModTime time.Time
+	Size    int64
+}
+
+// Snapshot is the result of a glob that can be compared against the file
+// system later with Rescan to find out which matches were added, removed, or
+// modified. Besides the matches, a Snapshot remembers the listing and
+// modification time of every directory that was read, so Rescan can skip
+// reading directories that have not changed.
+type Snapshot struct {
+	// Pattern is the pattern that was globbed.
+	Pattern string
+
+	// Files maps each match to its modification time and size.
+	Files map[string]FileState
+
+	opts []GlobOption
+	dirs map[string]snapshotDir
+}
+
+// Changes is returned by Rescan. Each list is sorted.
+type Changes struct {
+	Added    []string
+	Removed  []string
+	Modified []string
+}
+
+// GlobSnapshot globs `pattern`, just like Glob, and returns a Snapshot of the
+// matches along with their modification times and sizes. The options are
+// remembered so that Rescan will use them, too.
+//
+// If a match is a symlink, its state is that of the file it points to, unless
+// the symlink is broken. If WithCollectErrors is passed and I/O errors were
+// encountered, both the Snapshot and an *IOErrors are returned.
+func GlobSnapshot(fsys fs.FS, pattern string, opts ...GlobOption) (*Snapshot, error) {
+	return takeSnapshot(fsys, pattern, opts[:len(opts):len(opts)], nil)
+}
+
+// Rescan globs the pattern of `snapshot` again, with the same options, and
+// returns a new Snapshot along with the matches that were added, removed, or
+// modified (ie, their modification time or size changed) since `snapshot` was
+// taken. `snapshot` is not modified, so it may be rescanned again.
+//
+// Directories whose modification time has not changed since `snapshot` was
+// taken are not read again: their recorded listing is reused. Only matches
+// are Stat'd to detect modifications, so polling a large tree is cheap. This
+// relies on the file system updating a directory's modification time when
+// entries are added or removed, which most do. If the fs.FS does not report a
+// modification time for a directory, or the directory was modified at the
+// time it was read (within the granularity of a second, since that's all some
+// file systems record), it is always read again.
+func Rescan(fsys fs.FS, snapshot *Snapshot) (*Snapshot, Changes, error) {
+	next, err := takeSnapshot(fsys, snapshot.Pattern, snapshot.opts, snapshot.dirs)
+	if next == nil {
+		return nil, Changes{}, err
+	}
+
+	var changes Changes
+	for p, state := range next.Files {
+		prev, ok := snapshot.Files[p]
+		if !ok {
+			changes.Added = append(changes.Added, p)
+		} else if !prev.ModTime.Equal(state.ModTime) || prev.Size != state.Size {
+			changes.Modified = append(changes.Modified, p)
+		}
+	}
+	for p := range snapshot.Files {
+		if _, ok := next.Files[p]; !ok {
+			changes.Removed = append(changes.Removed, p)
+		}
+	}
+	sort.Strings(changes.Added)
+	sort.Strings(changes.Removed)
+	sort.Strings(changes.Modified)
+	return next, changes, err
+}
+
+// Globs `pattern`, reusing the directory listings in `prev` that are still
+// valid. Returns a nil Snapshot if the glob failed.
+func takeSnapshot(fsys fs.FS, pattern string, opts []GlobOption, prev map[string]snapshotDir) (*Snapshot, error) {
+	sfs := &snapshotFS{fsys: fsys, prev: prev, dirs: make(map[string]snapshotDir)}
+	s := &Snapshot{
+		Pattern: pattern,
+		Files:   make(map[string]FileState),
+		opts:    opts,
+		dirs:    sfs.dirs,
+	}
+
+	err := GlobWalk(sfs, pattern, func(p string, d fs.DirEntry) error {
+		info, err := fs.Stat(fsys, p)
+		if err != nil {
+			// broken symlinks match, but cannot be Stat'd
+			if info, err = d.Info(); err != nil {
+				if errors.Is(err, fs.ErrNotExist) {
+					// removed since its directory was read
+					return nil
+				}
+				s.Files[p] = FileState{}
+				return nil
+			}
+		}
+		s.Files[p] = FileState{ModTime: info.ModTime(), Size: info.Size()}
+		return nil
+	}, opts...)
+	if _, partial := err.(*IOErrors); err != nil && !partial {
+		return nil, err
+	}
+	return s, err
+}
+
+// The listing of a directory recorded in a Snapshot
+type snapshotDir struct {
+	modTime time.Time
+	readAt  time.Time
+	entries []fs.DirEntry
+}
+
+// Returns true if the listing can be reused as long as the directory's
+// modification time has not changed. If the directory was modified in the
+// same second it was read, it might have been modified again after it was
+// read without changing its modification time.
+func (d snapshotDir) trusted() bool {
+	return !d.modTime.IsZero() && d.modTime.Before(d.readAt.Truncate(time.Second))
+}
+
+// snapshotFS wraps an fs.FS to record the listing of every directory that is
+// read, and to reuse listings from a previous Snapshot if the directory has
+// not changed.
+type snapshotFS struct {
+	fsys fs.FS
+	prev map[string]snapshotDir
+	dirs map[string]snapshotDir
+}
+
+func (s *snapshotFS) Open(name string) (fs.File, error) {
+	return s.fsys.Open(name)
+}
+
+func (s *snapshotFS) Stat(name string) (fs.FileInfo, error) {
+	return fs.Stat(s.fsys, name)
+}
+
+func (s *snapshotFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	if d, ok := s.dirs[name]; ok {
+		// already read during this glob
+		return copyDirEntries(d.entries), nil
+	}
+
+	readAt := time.Now()
+	info, err := fs.Stat(s.fsys, name)
+	if err != nil {
+		// let ReadDir report the error
+		return fs.ReadDir(s.fsys, name)
+	}
+	if d, ok := s.prev[name]; ok && d.trusted() && info.ModTime().Equal(d.modTime) {
+		s.dirs[name] = d
+		return copyDirEntries(d.entries), nil
+	}
+
+	entries, err := fs.ReadDir(s.fsys, name)
+	if err == nil && !info.ModTime().IsZero() {
+		s.dirs[name] = snapshotDir{modTime: info.ModTime(), readAt: readAt, entries: entries}
+		return copyDirEntries(entries), nil
+	}
+	return entries, err
+}
+
+func copyDirEntries(entries []fs.DirEntry) []fs.DirEntry {
+	c := make([]fs.DirEntry, len(entries))
+	copy(c, entries)
+	return c
+}
@@ -0,0 +1,119 @@
+package doublestar
+
+import (
+	"io/fs"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func TestRescan(t *testing.T) {
+	t0 := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	t1 := t0.Add(time.Hour)
+	dir := func(mtime time.Time) *fstest.MapFile {
+		return &fstest.MapFile{Mode: fs.ModeDir | 0755, ModTime: mtime}
+	}
+
+	fsys := newCountingFS(fstest.MapFS{
+		".":       dir(t0),
+		"a":       dir(t0),
+		"a/x.txt": {Data: []byte("x"), ModTime: t0},
+		"a/y.go":  {ModTime: t0},
+		"b":       dir(t0),
+		"b/y.txt": {ModTime: t0},
+		"b/c":     dir(t0),
+	})
+
+	snap, err := GlobSnapshot(fsys, "**/*.txt")
+	if err != nil {
+		t.Fatalf("GlobSnapshot(`**/*.txt`) error: %v", err)
+	}
+	expected := map[string]FileState{
+		"a/x.txt": {ModTime: t0, Size: 1},
+		"b/y.txt": {ModTime: t0},
+	}
+	if len(snap.Files) != len(expected) || snap.Files["a/x.txt"] != expected["a/x.txt"] || snap.Files["b/y.txt"] != expected["b/y.txt"] {
+		t.Fatalf("GlobSnapshot(`**/*.txt`).Files = %v want %v", snap.Files, expected)
+	}
+
+	tests := []struct {
+		change   func()
+		expected Changes
+		readDirs []string
+	}{
+		{func() {}, Changes{}, nil},
+		{func() {
+			fsys.MapFS["a/x.txt"] = &fstest.MapFile{Data: []byte("xx"), ModTime: t1}
+		}, Changes{Modified: []string{"a/x.txt"}}, nil},
+		{func() {
+			fsys.MapFS["a/z.txt"] = &fstest.MapFile{ModTime: t1}
+			fsys.MapFS["a"] = dir(t1)
+		}, Changes{Added: []string{"a/z.txt"}}, []string{"a"}},
+		{func() {
+			delete(fsys.MapFS, "b/y.txt")
+			fsys.MapFS["b"] = dir(t1)
+		}, Changes{Removed: []string{"b/y.txt"}}, []string{"b"}},
+		{func() {
+			// without a change to the directory's mtime, new files are not noticed
+			fsys.MapFS["b/c/d.txt"] = &fstest.MapFile{ModTime: t1}
+		}, Changes{}, nil},
+		{func() {
+			fsys.MapFS["b/c"] = dir(t1)
+		}, Changes{Added: []string{"b/c/d.txt"}}, []string{"b/c"}},
+	}
+
+	for idx, tt := range tests {
+		tt.change()
+		fsys.readDirs = make(map[string]int)
+
+		next, changes, err := Rescan(fsys, snap)
+		if err != nil {
+			t.Errorf("#%v. Rescan(`**/*.txt`) error: %v", idx, err)
+			continue
+		}
+		if !equalSlices(changes.Added, tt.expected.Added) || !equalSlices(changes.Removed, tt.expected.Removed) || !equalSlices(changes.Modified, tt.expected.Modified) {
+			t.Errorf("#%v. Rescan(`**/*.txt`) = %+v want %+v", idx, changes, tt.expected)
+		}
+		for _, d := range tt.readDirs {
+			if fsys.readDirs[d] != 1 {
+				t.Errorf("#%v. Rescan(`**/*.txt`) read %#q %v times want 1", idx, d, fsys.readDirs[d])
+			}
+		}
+		if len(fsys.readDirs) != len(tt.readDirs) {
+			t.Errorf("#%v. Rescan(`**/*.txt`) read %v want %v", idx, fsys.readDirs, tt.readDirs)
+		}
+		snap = next
+	}
+}
+
+func TestRescanUntrustedDirs(t *testing.T) {
+	t0 := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	fsys := newCountingFS(fstest.MapFS{
+		// directories with no modification time, or one that is not before the
+		// time they were read, must always be read again
+		"a":       {Mode: fs.ModeDir | 0755, ModTime: time.Now().Add(time.Hour)},
+		"a/x.txt": {ModTime: t0},
+		"b/y.txt": {ModTime: t0},
+	})
+
+	snap, err := GlobSnapshot(fsys, "{a,b}/*.txt", WithFilesOnly())
+	if err != nil || len(snap.Files) != 2 {
+		t.Fatalf("GlobSnapshot(`{a,b}/*.txt`) = %v, %v", snap, err)
+	}
+
+	for i := 0; i < 2; i++ {
+		fsys.readDirs = make(map[string]int)
+		var changes Changes
+		snap, changes, err = Rescan(fsys, snap)
+		if err != nil || changes.Added != nil || changes.Removed != nil || changes.Modified != nil {
+			t.Errorf("#%v. Rescan(`{a,b}/*.txt`) = %+v, %v want no changes", i, changes, err)
+		}
+		if fsys.readDirs["a"] != 1 || fsys.readDirs["b"] != 1 {
+			t.Errorf("#%v. Rescan(`{a,b}/*.txt`) read %v want a and b", i, fsys.readDirs)
+		}
+	}
+
+	if _, err := GlobSnapshot(fsys, "a/["); err != ErrBadPattern {
+		t.Errorf("GlobSnapshot(`a/[`) = %v want ErrBadPattern", err)
+	}
+}
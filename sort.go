@@ -0,0 +1,220 @@
+package doublestar
+
+import (
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+)
+
+// SortOrder describes the order in which Glob and GlobWalk return matches
+// when the WithSortOrder option is passed. SortOrders may be combined with a
+// bitwise or, for example: `SortNatural | SortDirsFirst`.
+type SortOrder uint8
+
+// SortLexical compares names byte-by-byte. This is the default if no other
+// name comparison is specified.
+const SortLexical SortOrder = 0
+
+const (
+	// SortNatural compares names such that runs of digits are compared by their
+	// numeric value, ie, "file2" sorts before "file10".
+	SortNatural SortOrder = 1 << iota
+
+	// SortDirsFirst sorts directories before files in the same parent
+	// directory.
+	SortDirsFirst
+
+	// SortBreadthFirst returns all matches at one depth before any matches at a
+	// deeper depth. Without this flag, matches are returned depth-first: a
+	// directory is followed by all of its descendants before its next sibling.
+	SortBreadthFirst
+)
+
+// Sorts matches according to the order set by WithSortOrder. The fs is used
+// to determine if symlinks point at directories when SortDirsFirst is used.
+func (g *glob) sortMatches(fsys fs.FS, matches []DirEntryWithFullPath) {
+	var isDir []bool
+	if g.sortOrder&SortDirsFirst != 0 {
+		isDir = make([]bool, len(matches))
+		for i, m := range matches {
+			isDir[i], _ = g.isDir(fsys, "", m.Path, m.Entry)
+		}
+	}
+
+	idx := make([]int, len(matches))
+	for i := range idx {
+		idx[i] = i
+	}
+	sort.SliceStable(idx, func(i, j int) bool {
+		a, b := idx[i], idx[j]
+		aIsDir, bIsDir := false, false
+		if isDir != nil {
+			aIsDir, bIsDir = isDir[a], isDir[b]
+		}
+		return g.comparePaths(matches[a].Path, aIsDir, matches[b].Path, bIsDir) < 0
+	})
+
+	sorted := make([]DirEntryWithFullPath, len(matches))
+	for i, j := range idx {
+		sorted[i] = matches[j]
+	}
+	copy(matches, sorted)
+}
+
+// Compares two slash-separated paths according to the order set by
+// WithSortOrder, returning a negative number if `a` sorts first, a positive
+// number if `b` sorts first, or 0 if they are equal. `aIsDir` and `bIsDir`
+// describe the last segment of each path, and are only used with
+// SortDirsFirst.
+func (g *glob) comparePaths(a string, aIsDir bool, b string, bIsDir bool) int {
+	if a == "." {
+		a = ""
+	}
+	if b == "." {
+		b = ""
+	}
+
+	if g.sortOrder&SortBreadthFirst != 0 {
+		if aDepth, bDepth := pathDepth(a), pathDepth(b); aDepth != bDepth {
+			return aDepth - bDepth
+		}
+	}
+
+	for {
+		if a == "" || b == "" {
+			// a parent directory always sorts before its descendants
+			return len(a) - len(b)
+		}
+
+		aSegment, aRest, aLast := nextSegment(a)
+		bSegment, bRest, bLast := nextSegment(b)
+		if g.sortOrder&SortDirsFirst != 0 {
+			aSegmentIsDir := !aLast || aIsDir
+			bSegmentIsDir := !bLast || bIsDir
+			if aSegmentIsDir != bSegmentIsDir {
+				if aSegmentIsDir {
+					return -1
+				}
+				return 1
+			}
+		}
+
+		var c int
+		if g.sortOrder&SortNatural != 0 {
+			c = compareNatural(aSegment, bSegment)
+		} else {
+			c = strings.Compare(aSegment, bSegment)
+		}
+		if c != 0 {
+			return c
+		}
+
+		a, b = aRest, bRest
+	}
+}
+
+// Returns the first segment of slash-separated path `p`, the rest of the path,
+// and whether or not the segment was the last one.
+func nextSegment(p string) (segment, rest string, last bool) {
+	if idx := strings.IndexByte(p, '/'); idx != -1 {
+		return p[:idx], p[idx+1:], false
+	}
+	return p, "", true
+}
+
+// Returns the number of segments in a slash-separated path
+func pathDepth(p string) int {
+	if p == "" {
+		return 0
+	}
+	return strings.Count(p, "/") + 1
+}
+
+// Compares `a` and `b` such that runs of digits are compared by their numeric
+// value. If two runs of digits have the same value, but a different number of
+// leading zeros, the one with fewer leading zeros sorts first.
+func compareNatural(a, b string) int {
+	for a != "" && b != "" {
+		aDigits, bDigits := isDigit(a[0]), isDigit(b[0])
+		if !aDigits || !bDigits {
+			if a[0] != b[0] {
+				return int(a[0]) - int(b[0])
+			}
+			a, b = a[1:], b[1:]
+			continue
+		}
+
+		aRun, bRun := digitRun(a), digitRun(b)
+		aNum, bNum := strings.TrimLeft(a[:aRun], "0"), strings.TrimLeft(b[:bRun], "0")
+		if len(aNum) != len(bNum) {
+			return len(aNum) - len(bNum)
+		}
+		if c := strings.Compare(aNum, bNum); c != 0 {
+			return c
+		}
+		if aRun != bRun {
+			return aRun - bRun
+		}
+		a, b = a[aRun:], b[bRun:]
+	}
+	return len(a) - len(b)
+}
+
+func isDigit(c byte) bool {
+	return '0' <= c && c <= '9'
+}
+
+// Returns the length of the run of digits at the beginning of s
+func digitRun(s string) int {
+	i := 0
+	for i < len(s) && isDigit(s[i]) {
+		i++
+	}
+	return i
+}
+
+// Calls `fn` for each match in order, handling SkipDir the same way GlobWalk
+// does: if `fn` returns SkipDir for a directory, no descendants of that
+// directory will be passed to `fn`. If `fn` returns SkipDir for a file, no
+// other matches in the file's parent directory will be passed to `fn`.
+func (g *glob) walkMatches(fsys fs.FS, matches []DirEntryWithFullPath, fn GlobWalkFunc) error {
+	var skipped map[string]bool
+	for _, m := range matches {
+		if skipped != nil && hasSkippedAncestor(m.Path, skipped) {
+			continue
+		}
+		if err := fn(m.Path, m.Entry); err != nil {
+			if err != SkipDir {
+				return err
+			}
+
+			isDir, err := g.isDir(fsys, "", m.Path, m.Entry)
+			if err != nil {
+				return err
+			}
+			if skipped == nil {
+				skipped = make(map[string]bool)
+			}
+			if isDir {
+				skipped[m.Path] = true
+			} else {
+				skipped[path.Dir(m.Path)] = true
+			}
+		}
+	}
+	return nil
+}
+
+// Returns true if `p` or any of its parent directories are in `skipped`
+func hasSkippedAncestor(p string, skipped map[string]bool) bool {
+	for {
+		if skipped[p] {
+			return true
+		}
+		if p == "." || p == "/" {
+			return false
+		}
+		p = path.Dir(p)
+	}
+}
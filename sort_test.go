@@ -0,0 +1,168 @@
+package doublestar
+
+import (
+	"io/fs"
+	"testing"
+	"testing/fstest"
+)
+
+var sortTestFS = fstest.MapFS{
+	"a-x":        {},
+	"a/b":        {},
+	"a/c/d":      {},
+	"a/c/e":      {},
+	"dir10/x":    {},
+	"dir2/x":     {},
+	"file1":      {},
+	"file010":    {},
+	"file10":     {},
+	"file2":      {},
+	"zzz/file":   {},
+	"zzz/a/file": {},
+}
+
+type SortTest struct {
+	pattern  string
+	order    SortOrder
+	expected []string
+}
+
+var sortTests = []SortTest{
+	{"**", SortLexical, []string{".", "a", "a/b", "a/c", "a/c/d", "a/c/e", "a-x", "dir10", "dir10/x", "dir2", "dir2/x", "file010", "file1", "file10", "file2", "zzz", "zzz/a", "zzz/a/file", "zzz/file"}},
+	{"**", SortNatural, []string{".", "a", "a/b", "a/c", "a/c/d", "a/c/e", "a-x", "dir2", "dir2/x", "dir10", "dir10/x", "file1", "file2", "file10", "file010", "zzz", "zzz/a", "zzz/a/file", "zzz/file"}},
+	{"**", SortDirsFirst, []string{".", "a", "a/c", "a/c/d", "a/c/e", "a/b", "dir10", "dir10/x", "dir2", "dir2/x", "zzz", "zzz/a", "zzz/a/file", "zzz/file", "a-x", "file010", "file1", "file10", "file2"}},
+	{"**", SortBreadthFirst, []string{".", "a", "a-x", "dir10", "dir2", "file010", "file1", "file10", "file2", "zzz", "a/b", "a/c", "dir10/x", "dir2/x", "zzz/a", "zzz/file", "a/c/d", "a/c/e", "zzz/a/file"}},
+	{"**", SortBreadthFirst | SortNatural | SortDirsFirst, []string{".", "a", "dir2", "dir10", "zzz", "a-x", "file1", "file2", "file10", "file010", "a/c", "a/b", "dir2/x", "dir10/x", "zzz/a", "zzz/file", "a/c/d", "a/c/e", "zzz/a/file"}},
+	{"{file10,zzz/**/file,a*,file2}", SortLexical, []string{"a", "a-x", "file10", "file2", "zzz/a/file", "zzz/file"}},
+	{"{file10,zzz/**/file,a*,file2}", SortBreadthFirst | SortNatural, []string{"a", "a-x", "file2", "file10", "zzz/file", "zzz/a/file"}},
+	{"*/x", SortNatural, []string{"dir2/x", "dir10/x"}},
+}
+
+func TestGlobWithSortOrder(t *testing.T) {
+	for idx, tt := range sortTests {
+		matches, err := Glob(sortTestFS, tt.pattern, WithSortOrder(tt.order))
+		if err != nil || !equalSlices(matches, tt.expected) {
+			t.Errorf("#%v. Glob(%#q, WithSortOrder(%v)) = %#v, %v want %#v, nil", idx, tt.pattern, tt.order, matches, err, tt.expected)
+		}
+
+		entries, err := GlobEntries(sortTestFS, tt.pattern, WithSortOrder(tt.order))
+		matches = nil
+		for _, m := range entries {
+			matches = append(matches, m.Path)
+		}
+		if err != nil || !equalSlices(matches, tt.expected) {
+			t.Errorf("#%v. GlobEntries(%#q, WithSortOrder(%v)) = %#v, %v want %#v, nil", idx, tt.pattern, tt.order, matches, err, tt.expected)
+		}
+	}
+}
+
+func TestGlobWalkWithSortOrder(t *testing.T) {
+	for idx, tt := range sortTests {
+		var matches []string
+		err := GlobWalk(sortTestFS, tt.pattern, func(p string, d fs.DirEntry) error {
+			matches = append(matches, p)
+			return nil
+		}, WithSortOrder(tt.order))
+		if err != nil || !equalSlices(matches, tt.expected) {
+			t.Errorf("#%v. GlobWalk(%#q, WithSortOrder(%v)) = %#v, %v want %#v, nil", idx, tt.pattern, tt.order, matches, err, tt.expected)
+		}
+	}
+}
+
+func TestGlobWalkWithSortOrderSkipDir(t *testing.T) {
+	var matches []string
+	err := GlobWalk(sortTestFS, "**", func(p string, d fs.DirEntry) error {
+		if p == "a/c" || p == "dir10/x" {
+			return SkipDir
+		}
+		matches = append(matches, p)
+		return nil
+	}, WithSortOrder(SortBreadthFirst))
+
+	expected := []string{".", "a", "a-x", "dir10", "dir2", "file010", "file1", "file10", "file2", "zzz", "a/b", "dir2/x", "zzz/a", "zzz/file", "zzz/a/file"}
+	if err != nil || !equalSlices(matches, expected) {
+		t.Errorf("GlobWalk(`**`, WithSortOrder(SortBreadthFirst)) with SkipDir = %#v, %v want %#v, nil", matches, err, expected)
+	}
+}
+
+func TestGlobWalkBreadthFirstOrder(t *testing.T) {
+	// fstest.MapFS returns directory entries sorted by name, so breadth-first
+	// traversal of it is deterministic
+	tests := []struct {
+		pattern  string
+		expected []string
+	}{
+		{"**", []string{".", "a", "a-x", "dir10", "dir2", "file010", "file1", "file10", "file2", "zzz", "a/b", "a/c", "dir10/x", "dir2/x", "zzz/a", "zzz/file", "a/c/d", "a/c/e", "zzz/a/file"}},
+		{"**/file", []string{"zzz/file", "zzz/a/file"}},
+		{"zzz/**", []string{"zzz", "zzz/a", "zzz/file", "zzz/a/file"}},
+	}
+
+	for idx, tt := range tests {
+		var matches []string
+		err := GlobWalk(sortTestFS, tt.pattern, func(p string, d fs.DirEntry) error {
+			matches = append(matches, p)
+			return nil
+		}, WithBreadthFirstTraversal())
+		if err != nil || !equalSlices(matches, tt.expected) {
+			t.Errorf("#%v. GlobWalk(%#q, WithBreadthFirstTraversal()) = %#v, %v want %#v, nil", idx, tt.pattern, matches, err, tt.expected)
+		}
+
+		matches, err = Glob(sortTestFS, tt.pattern, WithBreadthFirstTraversal())
+		if err != nil || !equalSlices(matches, tt.expected) {
+			t.Errorf("#%v. Glob(%#q, WithBreadthFirstTraversal()) = %#v, %v want %#v, nil", idx, tt.pattern, matches, err, tt.expected)
+		}
+	}
+}
+
+func TestGlobWalkBreadthFirstSkipDir(t *testing.T) {
+	var matches []string
+	err := GlobWalk(sortTestFS, "**", func(p string, d fs.DirEntry) error {
+		if p == "a/b" || p == "zzz/a" {
+			return SkipDir
+		}
+		matches = append(matches, p)
+		return nil
+	}, WithBreadthFirstTraversal())
+
+	// skipping the file `a/b` skips the rest of `a`, and skipping the directory
+	// `zzz/a` skips its contents
+	expected := []string{".", "a", "a-x", "dir10", "dir2", "file010", "file1", "file10", "file2", "zzz", "dir10/x", "dir2/x", "zzz/file"}
+	if err != nil || !equalSlices(matches, expected) {
+		t.Errorf("GlobWalk(`**`, WithBreadthFirstTraversal()) with SkipDir = %#v, %v want %#v, nil", matches, err, expected)
+	}
+}
+
+func TestCompareNatural(t *testing.T) {
+	tests := []struct {
+		a, b     string
+		expected int
+	}{
+		{"a", "a", 0},
+		{"a", "b", -1},
+		{"a2", "a10", -1},
+		{"a02", "a2", 1},
+		{"a2b", "a2c", -1},
+		{"a2", "a2b", -1},
+		{"10", "9", 1},
+		{"x1y10", "x1y9", 1},
+	}
+
+	for idx, tt := range tests {
+		c := compareNatural(tt.a, tt.b)
+		if (c < 0 && tt.expected >= 0) || (c > 0 && tt.expected <= 0) || (c == 0 && tt.expected != 0) {
+			t.Errorf("#%v. compareNatural(%#q, %#q) = %v want %v", idx, tt.a, tt.b, c, tt.expected)
+		}
+	}
+}
+
+func equalSlices(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
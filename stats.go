@@ -0,0 +1,230 @@
+package doublestar
+
+import (
+	"io/fs"
+	"path"
+	"strconv"
+	"time"
+)
+
+// Stats records what doublestar did while globbing, which is useful to
+// explain why a glob was slow. See WithStats.
+//
+// Counters and durations are added to, never reset, so the same Stats may be
+// used to accumulate totals over several calls. A Stats must not be shared by
+// concurrent calls.
+type Stats struct {
+	// DirsRead is the number of times a directory was read with fs.ReadDir,
+	// including reads that failed.
+	DirsRead int
+
+	// EntriesExamined is the number of directory entries returned by
+	// fs.ReadDir.
+	EntriesExamined int
+
+	// StatCalls is the number of times fs.Stat was called, for example, to
+	// check if a path before any meta characters exists, or to determine if a
+	// symlink points to a directory.
+	StatCalls int
+
+	// SymlinksFollowed is the number of symlinks that were resolved with
+	// fs.Stat to determine if they point to a directory.
+	SymlinksFollowed int
+
+	// AltsExpanded is the number of alternatives of an alt (ie, `{...}`) that
+	// were globbed. A pattern such as `{a,b}/{c,d}` may expand the inner alt
+	// once for every match of the outer one.
+	AltsExpanded int
+
+	// DirsPruned is the number of directories that were not read because the
+	// Enter callback of GlobWalkDir or a GlobWalk callback returned SkipDir, or
+	// because they are hidden and WithSkipHidden was passed.
+	DirsPruned int
+
+	// ReadDirTime is the wall time spent in fs.ReadDir.
+	ReadDirTime time.Duration
+
+	// StatTime is the wall time spent in fs.Stat.
+	StatTime time.Duration
+
+	// MatchTime is the wall time spent matching names against pattern
+	// segments.
+	MatchTime time.Duration
+
+	// SortTime is the wall time spent sorting matches for WithSortOrder.
+	SortTime time.Duration
+
+	// TotalTime is the wall time of the whole call, including time spent in
+	// GlobWalk callbacks.
+	TotalTime time.Duration
+}
+
+// EventKind is the kind of an Event.
+type EventKind int
+
+const (
+	// EventReadDir is emitted after a directory has been read with fs.ReadDir.
+	EventReadDir EventKind = iota
+
+	// EventStat is emitted after fs.Stat has been called on a path.
+	EventStat
+
+	// EventMatch is emitted after a name has been matched against a segment
+	// of the pattern. Names walked by `**` are not matched against anything, so
+	// they do not emit this event.
+	EventMatch
+)
+
+// String returns the name of the kind, such as "ReadDir".
+func (k EventKind) String() string {
+	switch k {
+	case EventReadDir:
+		return "ReadDir"
+	case EventStat:
+		return "Stat"
+	case EventMatch:
+		return "Match"
+	}
+	return "EventKind(" + strconv.Itoa(int(k)) + ")"
+}
+
+// Event describes a single operation while globbing. See WithTrace.
+type Event struct {
+	Kind EventKind
+
+	// Path is the path, relative to the fs.FS, that was read, stat'd, or
+	// matched.
+	Path string
+
+	// Pattern is the segment of the pattern that the last segment of Path was
+	// matched against. Only set for EventMatch.
+	Pattern string
+
+	// Matched is true if the last segment of Path matched Pattern. Only set for
+	// EventMatch.
+	Matched bool
+
+	// Entries is the number of entries that were read. Only set for
+	// EventReadDir.
+	Entries int
+
+	// Err is the error returned by fs.ReadDir or fs.Stat, if any.
+	Err error
+
+	// Duration is the wall time of the operation.
+	Duration time.Duration
+}
+
+// WithStats is an option that can be passed to Glob, GlobWalk, GlobWalkDir,
+// GlobEntries, FilepathGlob, or GlobRoot. If passed, doublestar records
+// counters and timings in `stats` while globbing: how many directories were
+// read, how many entries were examined, how many times fs.Stat was called,
+// and so on. See Stats.
+//
+// Timings require reading the clock around every operation, so this option
+// has a small cost of its own.
+func WithStats(stats *Stats) GlobOption {
+	return func(g *glob) {
+		g.stats = stats
+	}
+}
+
+// WithTrace is an option that can be passed to Glob, GlobWalk, GlobWalkDir,
+// GlobEntries, FilepathGlob, or GlobRoot. If passed, `trace` is called after
+// every fs.ReadDir and fs.Stat, and every time a name is matched against a
+// segment of the pattern, with an Event describing what happened. `trace` is
+// called synchronously, so a slow function will slow down globbing.
+func WithTrace(trace func(Event)) GlobOption {
+	return func(g *glob) {
+		g.trace = trace
+	}
+}
+
+// Returns true if WithStats or WithTrace was passed.
+func (g *glob) instrumented() bool {
+	return g.stats != nil || g.trace != nil
+}
+
+// Calls fs.ReadDir, recording it for WithStats and WithTrace
+func (g *glob) readDir(fsys fs.FS, dir string) ([]fs.DirEntry, error) {
+	if !g.instrumented() {
+		return fs.ReadDir(fsys, dir)
+	}
+
+	start := time.Now()
+	entries, err := fs.ReadDir(fsys, dir)
+	d := time.Since(start)
+	if g.stats != nil {
+		g.stats.DirsRead++
+		g.stats.EntriesExamined += len(entries)
+		g.stats.ReadDirTime += d
+	}
+	if g.trace != nil {
+		g.trace(Event{Kind: EventReadDir, Path: dir, Entries: len(entries), Err: err, Duration: d})
+	}
+	return entries, err
+}
+
+// Calls fs.Stat, recording it for WithStats and WithTrace
+func (g *glob) stat(fsys fs.FS, name string) (fs.FileInfo, error) {
+	if !g.instrumented() {
+		return fs.Stat(fsys, name)
+	}
+
+	start := time.Now()
+	info, err := fs.Stat(fsys, name)
+	d := time.Since(start)
+	if g.stats != nil {
+		g.stats.StatCalls++
+		g.stats.StatTime += d
+	}
+	if g.trace != nil {
+		g.trace(Event{Kind: EventStat, Path: name, Err: err, Duration: d})
+	}
+	return info, err
+}
+
+// Matches the entry `name` in `dir` against a segment of the pattern,
+// recording it for WithStats and WithTrace
+func (g *glob) matchEntry(pattern, dir, name string) (bool, error) {
+	if !g.instrumented() {
+		return g.matchWithSeparator(pattern, g.normalize(name), '/', false)
+	}
+
+	start := time.Now()
+	matched, err := g.matchWithSeparator(pattern, g.normalize(name), '/', false)
+	d := time.Since(start)
+	if g.stats != nil {
+		g.stats.MatchTime += d
+	}
+	if g.trace != nil && err == nil {
+		g.trace(Event{Kind: EventMatch, Path: path.Join(dir, name), Pattern: pattern, Matched: matched, Duration: d})
+	}
+	return matched, err
+}
+
+// Records that a symlink was followed
+func (g *glob) countSymlinkFollowed() {
+	if g.stats != nil {
+		g.stats.SymlinksFollowed++
+	}
+}
+
+// Records that an alternative of an alt was expanded
+func (g *glob) countAltExpanded() {
+	if g.stats != nil {
+		g.stats.AltsExpanded++
+	}
+}
+
+// Records that a directory was pruned
+func (g *glob) countDirPruned() {
+	if g.stats != nil {
+		g.stats.DirsPruned++
+	}
+}
+
+// Adds the time since `start` to `*d`. Meant to be deferred.
+func addSince(d *time.Duration, start time.Time) {
+	*d += time.Since(start)
+}
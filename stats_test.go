@@ -0,0 +1,195 @@
+package doublestar
+
+import (
+	"io/fs"
+	"os"
+	"testing"
+	"testing/fstest"
+)
+
+var statsTestFS = fstest.MapFS{
+	"a/x.txt":   {},
+	"a/y.go":    {},
+	"b/c/z.txt": {},
+	"b/d.txt":   {},
+}
+
+func TestGlobWithStats(t *testing.T) {
+	tests := []struct {
+		pattern  string
+		opts     []GlobOption
+		expected Stats
+	}{
+		{"a/*.txt", nil, Stats{DirsRead: 1, EntriesExamined: 2}},
+		{"a/x.txt", nil, Stats{StatCalls: 1}},
+		{"{a,b}/*.txt", nil, Stats{DirsRead: 2, EntriesExamined: 4, StatCalls: 2, AltsExpanded: 2}},
+		{"*/*.txt", nil, Stats{DirsRead: 3, EntriesExamined: 6}},
+		{"**/*.txt", nil, Stats{DirsRead: 8, EntriesExamined: 14, StatCalls: 1}},
+		{"**/*.txt", []GlobOption{WithBreadthFirstTraversal()}, Stats{DirsRead: 8, EntriesExamined: 14, StatCalls: 1}},
+		{"b/**", []GlobOption{WithSortOrder(SortLexical)}, Stats{DirsRead: 2, EntriesExamined: 3, StatCalls: 1}},
+	}
+
+	for idx, tt := range tests {
+		var stats Stats
+		if _, err := Glob(statsTestFS, tt.pattern, append(tt.opts, WithStats(&stats))...); err != nil {
+			t.Errorf("#%v. Glob(%#q, WithStats) error: %v", idx, tt.pattern, err)
+			continue
+		}
+		if !equalCounters(stats, tt.expected) {
+			t.Errorf("#%v. Glob(%#q, WithStats) = %+v want %+v", idx, tt.pattern, stats, tt.expected)
+		}
+		if stats.TotalTime < stats.ReadDirTime+stats.StatTime+stats.MatchTime+stats.SortTime {
+			t.Errorf("#%v. Glob(%#q, WithStats) TotalTime %v is less than the sum of the other times: %+v", idx, tt.pattern, stats.TotalTime, stats)
+		}
+	}
+
+	// stats accumulate
+	var stats Stats
+	Glob(statsTestFS, "a/*", WithStats(&stats))
+	GlobWalk(statsTestFS, "a/*", func(string, fs.DirEntry) error { return nil }, WithStats(&stats))
+	if stats.DirsRead != 2 || stats.EntriesExamined != 4 {
+		t.Errorf("Glob(`a/*`) and GlobWalk(`a/*`) with the same Stats = %+v want DirsRead 2, EntriesExamined 4", stats)
+	}
+}
+
+func TestGlobWalkWithStatsDirsPruned(t *testing.T) {
+	var stats Stats
+	err := GlobWalk(statsTestFS, "**", func(p string, d fs.DirEntry) error {
+		if p == "b" {
+			return SkipDir
+		}
+		return nil
+	}, WithStats(&stats))
+	if err != nil || stats.DirsPruned != 1 || stats.DirsRead != 2 {
+		t.Errorf("GlobWalk(`**`) skipping `b` = %v, %+v want nil, DirsPruned 1, DirsRead 2", err, stats)
+	}
+
+	stats = Stats{}
+	err = GlobWalkDir(statsTestFS, "**/*.txt", GlobWalkOptions{
+		Enter: func(dir string) error {
+			if dir == "b/c" {
+				return SkipDir
+			}
+			return nil
+		},
+	}, WithStats(&stats))
+	if err != nil || stats.DirsPruned != 1 {
+		t.Errorf("GlobWalkDir(`**/*.txt`) skipping `b/c` = %v, %+v want nil, DirsPruned 1", err, stats)
+	}
+
+	// hidden directories that `**` skips are pruned, too, but hidden files are
+	// not counted
+	hiddenFS := fstest.MapFS{
+		"a.txt":      {},
+		".git/x.txt": {},
+		"b/.cache/y": {},
+		"b/.hidden":  {},
+		"b/c/d.txt":  {},
+	}
+	stats = Stats{}
+	err = GlobWalk(hiddenFS, "**", func(p string, d fs.DirEntry) error { return nil }, WithSkipHidden(), WithStats(&stats))
+	if err != nil || stats.DirsPruned != 2 {
+		t.Errorf("GlobWalk(`**`, WithSkipHidden()) = %v, %+v want nil, DirsPruned 2", err, stats)
+	}
+
+	stats = Stats{}
+	_, err = Glob(hiddenFS, "**/*.txt", WithSkipHidden(), WithStats(&stats))
+	if err != nil || stats.DirsPruned != 2 {
+		t.Errorf("Glob(`**/*.txt`, WithSkipHidden()) = %v, %+v want nil, DirsPruned 2", err, stats)
+	}
+
+	stats = Stats{}
+	err = GlobWalkDir(hiddenFS, "**/*.txt", GlobWalkOptions{}, WithSkipHidden(), WithStats(&stats))
+	if err != nil || stats.DirsPruned != 2 {
+		t.Errorf("GlobWalkDir(`**/*.txt`, WithSkipHidden()) = %v, %+v want nil, DirsPruned 2", err, stats)
+	}
+}
+
+func TestGlobWithStatsSymlinks(t *testing.T) {
+	if onWindows {
+		t.Skip("symlinks are not created on Windows")
+	}
+
+	var stats Stats
+	matches, err := Glob(os.DirFS("test"), "b/*/f", WithStats(&stats))
+	if err != nil || len(matches) != 1 || stats.SymlinksFollowed != 1 {
+		t.Errorf("Glob(`b/*/f`, WithStats) = %v, %v, %+v want [b/symlink-dir/f], nil, SymlinksFollowed 1", matches, err, stats)
+	}
+}
+
+func TestGlobWithTrace(t *testing.T) {
+	var events []Event
+	trace := func(e Event) {
+		e.Duration = 0
+		events = append(events, e)
+	}
+
+	if _, err := Glob(statsTestFS, "a/*.txt", WithTrace(trace)); err != nil {
+		t.Fatalf("Glob(`a/*.txt`, WithTrace) error: %v", err)
+	}
+	expected := []Event{
+		{Kind: EventReadDir, Path: "a", Entries: 2},
+		{Kind: EventMatch, Path: "a/x.txt", Pattern: "*.txt", Matched: true},
+		{Kind: EventMatch, Path: "a/y.go", Pattern: "*.txt", Matched: false},
+	}
+	if !equalEvents(events, expected) {
+		t.Errorf("Glob(`a/*.txt`, WithTrace) events = %+v want %+v", events, expected)
+	}
+
+	events = nil
+	if _, err := Glob(statsTestFS, "nope/x", WithTrace(trace)); err != nil {
+		t.Fatalf("Glob(`nope/x`, WithTrace) error: %v", err)
+	}
+	if len(events) != 1 || events[0].Kind != EventStat || events[0].Path != "nope/x" || events[0].Err == nil {
+		t.Errorf("Glob(`nope/x`, WithTrace) events = %+v want a failed Stat of nope/x", events)
+	}
+
+	events = nil
+	fsys := errFS{MapFS: statsTestFS, readDirErrs: map[string]bool{"a": true}}
+	if _, err := Glob(fsys, "a/*", WithTrace(trace)); err != nil {
+		t.Fatalf("Glob(`a/*`, WithTrace) error: %v", err)
+	}
+	if len(events) == 0 || events[0].Kind != EventReadDir || events[0].Err == nil {
+		t.Errorf("Glob(`a/*`, WithTrace) events = %+v want a failed ReadDir of a", events)
+	}
+}
+
+func TestEventKindString(t *testing.T) {
+	tests := []struct {
+		kind     EventKind
+		expected string
+	}{
+		{EventReadDir, "ReadDir"},
+		{EventStat, "Stat"},
+		{EventMatch, "Match"},
+		{EventKind(42), "EventKind(42)"},
+	}
+
+	for idx, tt := range tests {
+		if s := tt.kind.String(); s != tt.expected {
+			t.Errorf("#%v. EventKind(%d).String() = %q want %q", idx, int(tt.kind), s, tt.expected)
+		}
+	}
+}
+
+// Compares the counters of two Stats, ignoring times
+func equalCounters(a, b Stats) bool {
+	return a.DirsRead == b.DirsRead &&
+		a.EntriesExamined == b.EntriesExamined &&
+		a.StatCalls == b.StatCalls &&
+		a.SymlinksFollowed == b.SymlinksFollowed &&
+		a.AltsExpanded == b.AltsExpanded &&
+		a.DirsPruned == b.DirsPruned
+}
+
+func equalEvents(a, b []Event) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
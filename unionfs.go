@@ -0,0 +1,270 @@
+package doublestar
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+)
+
+// WhiteoutPrefix is the prefix of a whiteout in a UnionFS. A file named
+// `.wh.name` in one root hides `name` in every root after it, using the same
+// convention as overlay file systems and OCI image layers.
+const WhiteoutPrefix = ".wh."
+
+// OpaqueWhiteout is the name of a file that marks a directory in a UnionFS as
+// opaque: the directory's contents in every root after the one containing
+// the marker are hidden.
+const OpaqueWhiteout = WhiteoutPrefix + WhiteoutPrefix + ".opq"
+
+// UnionFS merges several fs.FS roots into one, with earlier roots shadowing
+// later ones. If a path exists in more than one root, the first root's file
+// is used. Directories are merged: reading a directory returns the entries
+// from every root that has it, without duplicates, so a single Glob over a
+// UnionFS returns each path once. If the first root that has a path has a
+// file there, rather than a directory, the directories in later roots are
+// hidden.
+//
+// Whiteouts remove paths from the union: a file named WhiteoutPrefix+name in
+// a root hides `name` in all later roots, and a directory containing an
+// OpaqueWhiteout file hides the same directory in all later roots. An
+// OpaqueWhiteout at the top of a root hides all later roots entirely.
+// Whiteouts themselves are never returned.
+//
+// Use Root to find out which root a path comes from, or GlobRoots to glob and
+// find out at once.
+type UnionFS struct {
+	roots []fs.FS
+}
+
+// NewUnionFS returns a UnionFS of `roots`, in order of precedence. If there
+// are no roots, nothing exists in the UnionFS, not even ".".
+func NewUnionFS(roots ...fs.FS) *UnionFS {
+	return &UnionFS{roots: roots}
+}
+
+// RootMatch is a match returned by GlobRoots.
+type RootMatch struct {
+	// Path is the path that matched.
+	Path string
+
+	// Root is the index of the root that the match comes from. If the match
+	// is a directory that exists in several roots, it is the first of them.
+	Root int
+}
+
+// GlobRoots globs `pattern` over the union of `roots`, just like calling Glob
+// on NewUnionFS(roots...), and returns each match along with the index of the
+// root that it comes from.
+func GlobRoots(roots []fs.FS, pattern string, opts ...GlobOption) ([]RootMatch, error) {
+	u := NewUnionFS(roots...)
+	matches, err := Glob(u, pattern, opts...)
+	if matches == nil {
+		return nil, err
+	}
+
+	results := make([]RootMatch, 0, len(matches))
+	for _, m := range matches {
+		// a pattern ending in a slash returns directories with a trailing slash
+		root, rerr := u.Root(strings.TrimSuffix(m, "/"))
+		if rerr != nil {
+			if errors.Is(rerr, fs.ErrNotExist) {
+				// removed since it was matched
+				continue
+			}
+			return nil, rerr
+		}
+		results = append(results, RootMatch{Path: m, Root: root})
+	}
+	return results, err
+}
+
+// unionPath is a path in a UnionFS resolved to the roots that provide it
+type unionPath struct {
+	// the root providing the path; for a directory, the first of `layers`
+	root int
+
+	// for a directory, the roots whose contents are merged; nil for a file
+	layers []int
+}
+
+func (u *UnionFS) resolve(op, name string) (unionPath, error) {
+	if !fs.ValidPath(name) {
+		return unionPath{}, &fs.PathError{Op: op, Path: name, Err: fs.ErrInvalid}
+	}
+
+	layers := make([]int, 0, len(u.roots))
+	for i := range u.roots {
+		layers = append(layers, i)
+		if u.exists(i, OpaqueWhiteout) {
+			break
+		}
+	}
+	if len(layers) == 0 {
+		// a union of nothing is empty
+		return unionPath{}, &fs.PathError{Op: op, Path: name, Err: fs.ErrNotExist}
+	}
+	if name == "." {
+		return unionPath{layers: layers}, nil
+	}
+
+	dir := "."
+	segments := strings.Split(name, "/")
+	for si, base := range segments {
+		if strings.HasPrefix(base, WhiteoutPrefix) {
+			return unionPath{}, &fs.PathError{Op: op, Path: name, Err: fs.ErrNotExist}
+		}
+		p := path.Join(dir, base)
+
+		top, isFile := -1, false
+		var next []int
+		for _, i := range layers {
+			info, err := fs.Stat(u.roots[i], p)
+			if err == nil {
+				if !info.IsDir() {
+					// a file hides everything after it
+					if top == -1 {
+						top, isFile = i, true
+					}
+					break
+				}
+				if top == -1 {
+					top = i
+				}
+				next = append(next, i)
+				if u.exists(i, path.Join(p, OpaqueWhiteout)) {
+					break
+				}
+			} else if !errors.Is(err, fs.ErrNotExist) {
+				return unionPath{}, err
+			}
+			if u.exists(i, path.Join(dir, WhiteoutPrefix+base)) {
+				break
+			}
+		}
+
+		if top == -1 || (isFile && si < len(segments)-1) {
+			return unionPath{}, &fs.PathError{Op: op, Path: name, Err: fs.ErrNotExist}
+		}
+		if isFile {
+			return unionPath{root: top}, nil
+		}
+		layers, dir = next, p
+	}
+	return unionPath{root: layers[0], layers: layers}, nil
+}
+
+func (u *UnionFS) exists(root int, name string) bool {
+	_, err := fs.Stat(u.roots[root], name)
+	return err == nil
+}
+
+// Root returns the index of the root that `name` comes from. If `name` is a
+// directory that exists in several roots, the first of them is returned.
+func (u *UnionFS) Root(name string) (int, error) {
+	p, err := u.resolve("root", name)
+	return p.root, err
+}
+
+// Open opens the named file from the root that provides it. Directories are
+// opened in the first root that has them, but their ReadDir method returns
+// the merged entries, just like UnionFS.ReadDir.
+func (u *UnionFS) Open(name string) (fs.File, error) {
+	p, err := u.resolve("open", name)
+	if err != nil {
+		return nil, err
+	}
+	f, err := u.roots[p.root].Open(name)
+	if err != nil || p.layers == nil {
+		return f, err
+	}
+
+	entries, err := u.readDir(name, p.layers)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	return &unionDir{File: f, entries: entries}, nil
+}
+
+// ReadDir implements fs.ReadDirFS. It returns the entries of `name` from
+// every root that has it, without duplicates, sorted by name.
+func (u *UnionFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	p, err := u.resolve("readdir", name)
+	if err != nil {
+		return nil, err
+	}
+	if p.layers == nil {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: errNotDir}
+	}
+	return u.readDir(name, p.layers)
+}
+
+func (u *UnionFS) readDir(name string, layers []int) ([]fs.DirEntry, error) {
+	var entries []fs.DirEntry
+	seen := make(map[string]bool)
+	hidden := make(map[string]bool)
+	for _, i := range layers {
+		rootEntries, err := fs.ReadDir(u.roots[i], name)
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				continue
+			}
+			return nil, err
+		}
+
+		var whiteouts []string
+		for _, e := range rootEntries {
+			n := e.Name()
+			if strings.HasPrefix(n, WhiteoutPrefix) {
+				if n != OpaqueWhiteout {
+					whiteouts = append(whiteouts, n[len(WhiteoutPrefix):])
+				}
+				continue
+			}
+			if !seen[n] && !hidden[n] {
+				seen[n] = true
+				entries = append(entries, e)
+			}
+		}
+
+		// whiteouts only hide entries in later roots
+		for _, n := range whiteouts {
+			hidden[n] = true
+		}
+	}
+
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name() < entries[j].Name() })
+	return entries, nil
+}
+
+// Stat implements fs.StatFS. It returns the information from the root that
+// provides `name`.
+func (u *UnionFS) Stat(name string) (fs.FileInfo, error) {
+	p, err := u.resolve("stat", name)
+	if err != nil {
+		return nil, err
+	}
+	return fs.Stat(u.roots[p.root], name)
+}
+
+// unionDir is a directory opened from a UnionFS
+type unionDir struct {
+	fs.File
+	entries []fs.DirEntry
+	offset  int
+}
+
+func (d *unionDir) ReadDir(n int) ([]fs.DirEntry, error) {
+	remaining := d.entries[d.offset:]
+	if n > 0 && len(remaining) == 0 {
+		return nil, io.EOF
+	}
+	if n > 0 && n < len(remaining) {
+		remaining = remaining[:n]
+	}
+	d.offset += len(remaining)
+	return append([]fs.DirEntry(nil), remaining...), nil
+}
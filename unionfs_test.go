@@ -0,0 +1,167 @@
+package doublestar
+
+import (
+	"errors"
+	"io/fs"
+	"reflect"
+	"testing"
+	"testing/fstest"
+)
+
+// a workspace, generated code, and a vendored SDK, in order of precedence
+var unionTestRoots = []fs.FS{
+	fstest.MapFS{
+		"main.go":              {Data: []byte("workspace")},
+		"pkg/a.go":             {Data: []byte("workspace")},
+		"pkg/.wh.b.go":         {},
+		"pkg/.wh.old":          {},
+		"sdk":                  {Data: []byte("not a dir")},
+		"api/.wh..wh..opq":     {},
+		"api/v2.go":            {},
+		"docs/.wh.README":      {},
+		"docs/README/shadowed": {},
+	},
+	fstest.MapFS{
+		"main.go":        {Data: []byte("generated")},
+		"pkg/b.go":       {},
+		"pkg/gen.go":     {},
+		"pkg/old/x.go":   {},
+		"api/v1.go":      {},
+		"docs/README":    {},
+		"gen/z.go":       {},
+		"gen/.wh.hidden": {},
+	},
+	fstest.MapFS{
+		"pkg/c.go":      {},
+		"pkg/b.go":      {},
+		"sdk/lib.go":    {},
+		"api/v0.go":     {},
+		"gen/hidden.go": {},
+		"gen/hidden":    {},
+		"vendor/v.go":   {},
+	},
+}
+
+func TestUnionFS(t *testing.T) {
+	u := NewUnionFS(unionTestRoots...)
+
+	expected := map[string]int{
+		"main.go":              0,
+		"pkg/a.go":             0,
+		"pkg/gen.go":           1,
+		"pkg/c.go":             2,
+		"sdk":                  0,
+		"api/v2.go":            0,
+		"docs/README/shadowed": 0,
+		"gen/z.go":             1,
+		"gen/hidden.go":        2,
+		"vendor/v.go":          2,
+	}
+	matches, err := GlobRoots(unionTestRoots, "**", WithFilesOnly())
+	if err != nil {
+		t.Fatalf("GlobRoots(`**`) error: %v", err)
+	}
+	for _, m := range matches {
+		root, ok := expected[m.Path]
+		if !ok {
+			t.Errorf("GlobRoots(`**`) returned %#q from root %v, which should be hidden", m.Path, m.Root)
+		} else if m.Root != root {
+			t.Errorf("GlobRoots(`**`) returned %#q from root %v want %v", m.Path, m.Root, root)
+		}
+		delete(expected, m.Path)
+	}
+	for p := range expected {
+		t.Errorf("GlobRoots(`**`) did not return %#q", p)
+	}
+
+	if b, err := fs.ReadFile(u, "main.go"); err != nil || string(b) != "workspace" {
+		t.Errorf("ReadFile(`main.go`) = %q, %v want `workspace`", b, err)
+	}
+	for _, name := range []string{"pkg/b.go", "pkg/old", "pkg/old/x.go", "sdk/lib.go", "api/v1.go", "gen/hidden", "pkg/.wh.b.go", "nope"} {
+		if _, err := fs.Stat(u, name); err == nil {
+			t.Errorf("Stat(%#q) has no error but should be hidden", name)
+		}
+	}
+	if root, err := u.Root("pkg"); err != nil || root != 0 {
+		t.Errorf("Root(`pkg`) = %v, %v want 0", root, err)
+	}
+
+	if err := fstest.TestFS(u, "main.go", "pkg/a.go", "pkg/gen.go", "pkg/c.go", "sdk", "gen/z.go", "vendor/v.go"); err != nil {
+		t.Errorf("UnionFS is not a valid fs.FS: %v", err)
+	}
+}
+
+func TestUnionFSOpaqueRoot(t *testing.T) {
+	// an opaque marker at the top of a root hides every later root
+	roots := []fs.FS{
+		fstest.MapFS{"a.go": {}, ".wh..wh..opq": {}},
+		fstest.MapFS{"b.go": {}, "c/d.go": {}},
+	}
+	expected := []RootMatch{{"a.go", 0}}
+	matches, err := GlobRoots(roots, "**", WithFilesOnly())
+	if err != nil || !reflect.DeepEqual(matches, expected) {
+		t.Errorf("GlobRoots(`**`) = %v, %v want %v", matches, err, expected)
+	}
+	for _, name := range []string{"b.go", "c", "c/d.go"} {
+		if _, err := fs.Stat(NewUnionFS(roots...), name); err == nil {
+			t.Errorf("Stat(%#q) has no error but should be hidden", name)
+		}
+	}
+}
+
+func TestUnionFSNoRoots(t *testing.T) {
+	u := NewUnionFS()
+	for _, name := range []string{".", "a"} {
+		if _, err := fs.Stat(u, name); !errors.Is(err, fs.ErrNotExist) {
+			t.Errorf("Stat(%#q) error = %v want fs.ErrNotExist", name, err)
+		}
+		if _, err := u.Open(name); !errors.Is(err, fs.ErrNotExist) {
+			t.Errorf("Open(%#q) error = %v want fs.ErrNotExist", name, err)
+		}
+	}
+	if matches, err := GlobRoots(nil, "**"); err != nil || len(matches) != 0 {
+		t.Errorf("GlobRoots(nil, `**`) = %v, %v want no matches", matches, err)
+	}
+}
+
+func TestUnionFSMatchesGlob(t *testing.T) {
+	// a union of roots without any overlap is the same as one fs
+	merged := fstest.MapFS{}
+	roots := []fs.FS{
+		fstest.MapFS{"a/b.go": {}, "a/c/d.go": {}},
+		fstest.MapFS{"a/e.go": {}, "f/g.go": {}},
+	}
+	for _, r := range roots {
+		for k, v := range r.(fstest.MapFS) {
+			merged[k] = v
+		}
+	}
+
+	for _, pattern := range []string{"**", "**/*.go", "a/*", "a/**/", "{a,f}/*.go", "nope/*"} {
+		expected, _ := Glob(merged, pattern)
+		matches, err := Glob(NewUnionFS(roots...), pattern)
+		if err != nil || !equalSlices(matches, expected) {
+			t.Errorf("Glob(UnionFS, %#q) = %v, %v want %v", pattern, matches, err, expected)
+		}
+	}
+
+	// a pattern ending in a slash matches directories with a trailing slash
+	slashTests := []struct {
+		pattern  string
+		expected []RootMatch
+	}{
+		{"a/", []RootMatch{{"a/", 0}}},
+		{"a/c/", []RootMatch{{"a/c/", 0}}},
+		{"*/", []RootMatch{{"a", 0}, {"f", 1}}},
+	}
+	for _, tt := range slashTests {
+		matches, err := GlobRoots(roots, tt.pattern)
+		if err != nil || !reflect.DeepEqual(matches, tt.expected) {
+			t.Errorf("GlobRoots(%#q) = %v, %v want %v", tt.pattern, matches, err, tt.expected)
+		}
+	}
+
+	if _, err := GlobRoots(roots, "a/["); err != ErrBadPattern {
+		t.Errorf("GlobRoots(`a/[`) error = %v want ErrBadPattern", err)
+	}
+}
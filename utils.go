@@ -87,9 +87,9 @@ func SplitPattern(p string) (base, pattern string) {
 // filepath.ErrBadPattern.
 //
 func FilepathGlob(pattern string, opts ...GlobOption) (matches []string, err error) {
+	g := newGlob(opts...)
 	if pattern == "" {
 		// special case to match filepath.Glob behavior
-		g := newGlob(opts...)
 		if g.failOnIOErrors {
 			// match doublestar.Glob behavior here
 			return nil, os.ErrInvalid
@@ -100,6 +100,15 @@ func FilepathGlob(pattern string, opts ...GlobOption) (matches []string, err err
 	pattern = filepath.Clean(pattern)
 	pattern = filepath.ToSlash(pattern)
 	base, f := SplitPattern(pattern)
+	if g.byteSemantics && base != "." && base != "/" {
+		// SplitPattern doesn't know about `\xNN` escapes
+		base = g.unescapeMeta(pattern[:len(pattern)-len(f)-1])
+	}
+	if filepath.Separator == '\\' {
+		// SplitPattern doesn't know about volume names, such as `C:` or
+		// `\\server\share`
+		base, f = WindowsPath{}.SplitPattern(pattern)
+	}
 	if f == "" || f == "." || f == ".." {
 		// some special cases to match filepath.Glob behavior
 		if !ValidatePathPattern(pattern) {
@@ -107,31 +116,66 @@ func FilepathGlob(pattern string, opts ...GlobOption) (matches []string, err err
 		}
 
 		if filepath.Separator != '\\' {
-			pattern = unescapeMeta(pattern)
+			pattern = g.unescapeMeta(pattern)
 		}
 
 		if _, err = os.Lstat(pattern); err != nil {
-			g := newGlob(opts...)
 			if errors.Is(err, os.ErrNotExist) {
 				return nil, g.handlePatternNotExist(true)
 			}
-			return nil, g.forwardErrIfFailOnIOErrors(err)
+			_, err = g.handleIOError("lstat", filepath.FromSlash(pattern), err)
+			return nil, g.collectedErrors(err)
 		}
 		return []string{filepath.FromSlash(pattern)}, nil
 	}
 
+	if g.normalizer != nil {
+		base, f = splitExistingBase(base, f)
+	}
+
 	fs := os.DirFS(base)
-	if matches, err = Glob(fs, f, opts...); err != nil {
+	matches, err = Glob(fs, f, opts...)
+	ioErrs, partial := err.(*IOErrors)
+	if err != nil && !partial {
 		return nil, err
 	}
+	if partial {
+		ioErrs.rebase(func(p string) string {
+			return filepath.Join(base, filepath.FromSlash(p))
+		})
+	}
 	for i := range matches {
-		// use path.Join because we used ToSlash above to ensure our paths are made
-		// of forward slashes, no matter what the system uses
-		matches[i] = filepath.FromSlash(path.Join(base, matches[i]))
+		// matches are made of forward slashes, no matter what the system uses, but
+		// on Windows, base may contain a volume name, such as `C:\`, which
+		// filepath.Join knows how to handle
+		matches[i] = filepath.Join(base, filepath.FromSlash(matches[i]))
 	}
 	return
 }
 
+// When WithUnicodeNormalization is enabled, the base path returned by
+// SplitPattern may not exist exactly as written. In that case, segments are
+// moved from the end of `base` to the beginning of `pattern` until `base`
+// exists, so that Glob can find them under normalization.
+func splitExistingBase(base, pattern string) (string, string) {
+	for base != "." && base != "/" {
+		if _, err := os.Stat(base); !errors.Is(err, os.ErrNotExist) {
+			break
+		}
+
+		dir, file := path.Split(base)
+		pattern = escapeMeta(file) + "/" + pattern
+		if dir == "" {
+			base = "."
+		} else if dir == "/" {
+			base = dir
+		} else {
+			base = dir[:len(dir)-1]
+		}
+	}
+	return base, pattern
+}
+
 // Finds the next comma, but ignores any commas that appear inside nested `{}`.
 // Assumes that each opening bracket has a corresponding closing bracket.
 func indexNextAlt(s string, allowEscaping bool) int {
@@ -158,3 +202,38 @@ var metaReplacer = strings.NewReplacer("\\*", "*", "\\?", "?", "\\[", "[", "\\]"
 func unescapeMeta(pattern string) string {
 	return metaReplacer.Replace(pattern)
 }
+
+var escapeMetaReplacer = strings.NewReplacer("*", "\\*", "?", "\\?", "[", "\\[", "]", "\\]", "{", "\\{", "}", "\\}")
+
+// Unescapes meta characters (*?[]{}) and, if WithByteSemantics is enabled,
+// `\xNN` escapes.
+func (g *glob) unescapeMeta(pattern string) string {
+	if !g.byteSemantics || strings.IndexByte(pattern, '\\') == -1 {
+		return unescapeMeta(pattern)
+	}
+
+	buf := make([]byte, 0, len(pattern))
+	l := len(pattern)
+	for i := 0; i < l; i++ {
+		if pattern[i] == '\\' && i+1 < l {
+			if b, ok := decodeHexEscape(pattern[i+1:]); ok {
+				buf = append(buf, b)
+				i += 3
+				continue
+			}
+			switch pattern[i+1] {
+			case '*', '?', '[', ']', '{', '}':
+				buf = append(buf, pattern[i+1])
+				i++
+				continue
+			}
+		}
+		buf = append(buf, pattern[i])
+	}
+	return string(buf)
+}
+
+// Escapes meta characters (*?[]{})
+func escapeMeta(s string) string {
+	return escapeMetaReplacer.Replace(s)
+}
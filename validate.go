@@ -1,6 +1,9 @@
 package doublestar
 
-import "path/filepath"
+import (
+	"path/filepath"
+	"unicode/utf8"
+)
 
 // Validate a pattern. Patterns are validated while they run in Match(),
 // PathMatch(), and Glob(), so, you normally wouldn't need to call this.
@@ -23,6 +26,25 @@ func ValidatePathPattern(s string) bool {
 	return doValidatePattern(s, filepath.Separator)
 }
 
+// Like ValidatePattern, only uses `separator` as the path separator. Use
+// ValidatePatternWithSeparator if you would normally use MatchWithSeparator().
+// Returns false if `separator` cannot be used as a separator (see
+// MatchWithSeparator).
+//
+func ValidatePatternWithSeparator(s string, separator rune) bool {
+	return isValidSeparator(separator) && doValidatePattern(s, separator)
+}
+
+// Returns false if `separator` is a meta character, which would make patterns
+// ambiguous.
+func isValidSeparator(separator rune) bool {
+	switch separator {
+	case '*', '?', '[', ']', '{', '}', ',', utf8.RuneError:
+		return false
+	}
+	return separator > 0 && utf8.ValidRune(separator)
+}
+
 func doValidatePattern(s string, separator rune) bool {
 	altDepth := 0
 	l := len(s)
@@ -80,3 +102,124 @@ VALIDATE:
 	// valid as long as all alts are closed
 	return altDepth == 0
 }
+
+// Returns false if the pattern contains a doublestar (`**`) that is not an
+// entire path segment by itself. Used to implement WithStrictDoubleStar.
+// Assumes the pattern has already been validated by doValidatePattern.
+//
+// Alts are taken into account: a doublestar at the beginning of an alt is at
+// the start of a segment if the alt itself is, and a doublestar at the end of
+// an alt is at the end of a segment if whatever follows the alt is.
+func validateDoubleStars(s string, separator rune) bool {
+	// for each level of nested alts, whether or not the alt began at the start
+	// of a path segment
+	var altStarts []bool
+	segmentStart := true
+	l := len(s)
+	for i := 0; i < l; i++ {
+		switch s[i] {
+		case '\\':
+			if separator != '\\' {
+				i++
+				segmentStart = false
+				continue
+			}
+
+		case '[':
+			// skip classes: stars are literal inside of them
+			i++
+			if i < l && (s[i] == '^' || s[i] == '!') {
+				i++
+			}
+			for i++; i < l && s[i] != ']'; i++ {
+				if separator != '\\' && s[i] == '\\' {
+					i++
+				}
+			}
+			segmentStart = false
+			continue
+
+		case '{':
+			altStarts = append(altStarts, segmentStart)
+			continue
+
+		case ',':
+			if len(altStarts) > 0 {
+				segmentStart = altStarts[len(altStarts)-1]
+				continue
+			}
+
+		case '}':
+			if len(altStarts) > 0 {
+				altStarts = altStarts[:len(altStarts)-1]
+				segmentStart = false
+				continue
+			}
+
+		case '*':
+			if i+1 < l && s[i+1] == '*' {
+				if !segmentStart || !isDoubleStarEnd(s, i+2, separator, true) {
+					return false
+				}
+				i++
+			}
+			segmentStart = false
+			continue
+		}
+
+		r, rl := utf8.DecodeRuneInString(s[i:])
+		segmentStart = r == separator
+		i += rl - 1
+	}
+	return true
+}
+
+// Returns true if the doublestar ending at `idx` is at the end of a path
+// segment: that is, `idx` is the end of the pattern, a separator, the end of
+// an alt which is, itself, followed by one of these, or an alt whose branches
+// all start with one of these. `atEnd` is returned if the end of `s` is
+// reached, which lets alt branches be checked against what follows the alt.
+func isDoubleStarEnd(s string, idx int, separator rune, atEnd bool) bool {
+	l := len(s)
+	allowEscaping := separator != '\\'
+	for idx < l {
+		switch s[idx] {
+		case ',':
+			// skip to the end of this alt
+			closingIdx := indexMatchedClosingAlt(s[idx+1:], allowEscaping)
+			if closingIdx == -1 {
+				return false
+			}
+			idx += closingIdx + 2
+		case '}':
+			idx++
+		case '{':
+			// every branch must end the doublestar's segment, or be followed by
+			// something after the alt that does
+			closingIdx := indexMatchedClosingAlt(s[idx+1:], allowEscaping)
+			if closingIdx == -1 {
+				return false
+			}
+			restAtEnd := isDoubleStarEnd(s, idx+closingIdx+2, separator, atEnd)
+			alts := s[idx+1 : idx+closingIdx+1]
+			for {
+				nextIdx := indexNextAlt(alts, allowEscaping)
+				branch := alts
+				if nextIdx != -1 {
+					branch = alts[:nextIdx]
+				}
+				if !isDoubleStarEnd(branch, 0, separator, restAtEnd) {
+					return false
+				}
+				if nextIdx == -1 {
+					return true
+				}
+				alts = alts[nextIdx+1:]
+			}
+		default:
+			r, _ := utf8.DecodeRuneInString(s[idx:])
+			return r == separator
+		}
+	}
+	return atEnd
+}
@@ -0,0 +1,328 @@
+package doublestar
+
+import (
+	"context"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// WatchOp describes what happened to a path reported by Watch.
+type WatchOp int
+
+const (
+	// WatchCreate means a new path matches one of the patterns.
+	WatchCreate WatchOp = iota + 1
+
+	// WatchRemove means a path no longer matches any of the patterns, usually
+	// because it was removed.
+	WatchRemove
+
+	// WatchModify means the modification time or size of a matching path
+	// changed.
+	WatchModify
+)
+
+// String returns the name of the op, such as "Create".
+func (op WatchOp) String() string {
+	switch op {
+	case WatchCreate:
+		return "Create"
+	case WatchRemove:
+		return "Remove"
+	case WatchModify:
+		return "Modify"
+	}
+	return "WatchOp(" + strconv.Itoa(int(op)) + ")"
+}
+
+// WatchEvent is sent by Watch when a path matching one of the patterns is
+// created, removed, or modified.
+type WatchEvent struct {
+	Op WatchOp
+
+	// Path is the path that changed, relative to the fs.FS.
+	Path string
+
+	// Err is set, and Op and Path are not, if the file system could not be
+	// read. See Watch.
+	Err error
+}
+
+// WatchOption is an option that can be passed to Watch.
+type WatchOption func(*watchOptions)
+
+// watchOptions are the settings used by Watch
+type watchOptions struct {
+	pollInterval time.Duration
+	inotifyRoot  string
+	globOpts     []GlobOption
+}
+
+// WithPollInterval is an option that can be passed to Watch to set how often
+// the file system is polled for changes. The default is one second.
+func WithPollInterval(d time.Duration) WatchOption {
+	return func(w *watchOptions) {
+		w.pollInterval = d
+	}
+}
+
+// WithInotify is an option that can be passed to Watch if the fs.FS is
+// os.DirFS(root). On Linux, Watch will then use inotify to find out when
+// directories change, instead of polling. It watches the directories that the
+// patterns need to read, as well as the literal prefix of each pattern (as
+// computed by SplitPattern) and its parents, so that it will notice if the
+// prefix is created later. On other systems, or if inotify cannot be used,
+// for example, because the limit on the number of watches has been reached,
+// Watch falls back to polling.
+func WithInotify(root string) WatchOption {
+	return func(w *watchOptions) {
+		w.inotifyRoot = root
+	}
+}
+
+// WithGlobOptions is an option that can be passed to Watch to set the options
+// that are passed to GlobSnapshot, such as WithFilesOnly or WithSkipHidden.
+// If passed more than once, all of the options are used.
+func WithGlobOptions(opts ...GlobOption) WatchOption {
+	return func(w *watchOptions) {
+		w.globOpts = append(w.globOpts, opts...)
+	}
+}
+
+// Watch watches `fsys` for paths matching any of `patterns` to be created,
+// removed, or modified, sending a WatchEvent on the returned channel for each
+// one. Paths that already match when Watch is called do not produce events.
+// Options passed with WithGlobOptions are passed to GlobSnapshot.
+//
+// By default, Watch polls by calling Rescan on every pattern, so it works
+// with any fs.FS; see Rescan for how it avoids reading directories that have
+// not changed. If several events are found at once, they are sent sorted by
+// path. A path matching more than one pattern is only reported once.
+//
+// The channel is closed once `ctx` is done. If a pattern is invalid, or the
+// file system cannot be read when Watch is called, a single WatchEvent with
+// Err set is sent before the channel is closed. Errors while watching, for
+// example, if WithFailOnIOErrors or WithCollectErrors is passed, are sent as
+// a WatchEvent with Err set, but watching continues.
+func Watch(ctx context.Context, fsys fs.FS, patterns []string, opts ...WatchOption) <-chan WatchEvent {
+	events := make(chan WatchEvent)
+	o := &watchOptions{}
+	for _, opt := range opts {
+		opt(o)
+	}
+	w := &watcher{
+		fsys:      fsys,
+		patterns:  patterns,
+		snapshots: make([]*Snapshot, len(patterns)),
+		events:    events,
+	}
+
+	for i, pattern := range patterns {
+		snap, err := GlobSnapshot(fsys, pattern, o.globOpts...)
+		if snap == nil {
+			go func() {
+				defer close(events)
+				select {
+				case events <- WatchEvent{Err: err}:
+				case <-ctx.Done():
+				}
+			}()
+			return events
+		}
+		w.snapshots[i] = snap
+	}
+
+	interval := o.pollInterval
+	if interval <= 0 {
+		interval = time.Second
+	}
+
+	var n notifier
+	if o.inotifyRoot != "" {
+		n, _ = newNotifier(o.inotifyRoot)
+	}
+	if n != nil {
+		if _, err := n.watch(w.dirs()); err != nil {
+			n.close()
+			n = nil
+		}
+	}
+
+	go w.run(ctx, interval, n)
+	return events
+}
+
+// notifier is implemented by OS-specific backends that report when watched
+// directories change.
+type notifier interface {
+	// C returns a channel that receives a value whenever a watched directory
+	// changes.
+	C() <-chan struct{}
+
+	// Watches `dirs`, paths relative to the root of the fs.FS, and stops
+	// watching any other directories. Returns true if any new directories are
+	// being watched. Directories that do not exist are ignored; any other
+	// error means that changes might be missed.
+	watch(dirs map[string]bool) (bool, error)
+
+	close() error
+}
+
+type watcher struct {
+	fsys      fs.FS
+	patterns  []string
+	snapshots []*Snapshot
+	events    chan<- WatchEvent
+}
+
+func (w *watcher) run(ctx context.Context, interval time.Duration, n notifier) {
+	defer close(w.events)
+
+	var tick <-chan time.Time
+	var notify <-chan struct{}
+	if n != nil {
+		defer n.close()
+		notify = n.C()
+
+		// changes made while the first watches were being added were missed
+		if !w.rescan(ctx) {
+			return
+		}
+	} else {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-tick:
+		case <-notify:
+		}
+
+		for {
+			if !w.rescan(ctx) {
+				return
+			}
+			if n == nil {
+				break
+			}
+
+			added, err := n.watch(w.dirs())
+			if err != nil {
+				// fall back to polling
+				n.close()
+				n, notify = nil, nil
+				ticker := time.NewTicker(interval)
+				defer ticker.Stop()
+				tick = ticker.C
+				break
+			}
+			if !added {
+				break
+			}
+			// new directories may have changed before they were watched
+		}
+	}
+}
+
+// Rescans every pattern and sends events for the changes. Returns false if
+// `ctx` is done.
+func (w *watcher) rescan(ctx context.Context) bool {
+	ops := make(map[string]WatchOp)
+	var errs []error
+	for i, snap := range w.snapshots {
+		next, changes, err := Rescan(w.fsys, snap)
+		if err != nil {
+			errs = append(errs, err)
+		}
+		if next == nil {
+			continue
+		}
+		w.snapshots[i] = next
+
+		for _, p := range changes.Added {
+			ops[p] = WatchCreate
+		}
+		for _, p := range changes.Removed {
+			if _, ok := ops[p]; !ok {
+				ops[p] = WatchRemove
+			}
+		}
+		for _, p := range changes.Modified {
+			ops[p] = WatchModify
+		}
+	}
+
+	// a path that was removed as a match of one pattern, but still matches
+	// another, has not been removed
+	for p, op := range ops {
+		if op == WatchRemove && w.matches(p) {
+			delete(ops, p)
+		}
+	}
+
+	paths := make([]string, 0, len(ops))
+	for p := range ops {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+
+	for _, err := range errs {
+		if !w.send(ctx, WatchEvent{Err: err}) {
+			return false
+		}
+	}
+	for _, p := range paths {
+		if !w.send(ctx, WatchEvent{Op: ops[p], Path: p}) {
+			return false
+		}
+	}
+	return ctx.Err() == nil
+}
+
+// Returns true if `p` is in any of the snapshots
+func (w *watcher) matches(p string) bool {
+	for _, snap := range w.snapshots {
+		if _, ok := snap.Files[p]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+func (w *watcher) send(ctx context.Context, e WatchEvent) bool {
+	select {
+	case w.events <- e:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// Returns the directories that a notifier should watch: every directory that
+// was read by the last scan, and the literal prefix of every pattern, along
+// with its parents.
+func (w *watcher) dirs() map[string]bool {
+	dirs := make(map[string]bool)
+	for i, snap := range w.snapshots {
+		for dir := range snap.dirs {
+			dirs[dir] = true
+		}
+
+		base, _ := SplitPattern(w.patterns[i])
+		for {
+			dirs[base] = true
+			if base == "." || base == "/" {
+				break
+			}
+			base = path.Dir(base)
+		}
+	}
+	return dirs
+}
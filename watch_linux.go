@@ -0,0 +1,92 @@
+//go:build linux
+// +build linux
+
+package doublestar
+
+import (
+	"os"
+	"path/filepath"
+	"syscall"
+)
+
+const inotifyMask = syscall.IN_CREATE | syscall.IN_DELETE | syscall.IN_MODIFY |
+	syscall.IN_ATTRIB | syscall.IN_CLOSE_WRITE | syscall.IN_MOVED_FROM |
+	syscall.IN_MOVED_TO | syscall.IN_DELETE_SELF | syscall.IN_MOVE_SELF |
+	syscall.IN_ONLYDIR
+
+// inotify is a notifier that uses Linux's inotify. It doesn't care what the
+// events are: any event means that Watch should rescan.
+type inotify struct {
+	root string
+	fd   int
+	f    *os.File
+	wds  map[string]int
+	c    chan struct{}
+}
+
+func newNotifier(root string) (notifier, error) {
+	fd, err := syscall.InotifyInit1(syscall.IN_CLOEXEC | syscall.IN_NONBLOCK)
+	if err != nil {
+		return nil, os.NewSyscallError("inotify_init1", err)
+	}
+
+	// because the fd is non-blocking, reads will use the runtime's poller, so
+	// closing the file will interrupt a pending read
+	n := &inotify{
+		root: root,
+		fd:   fd,
+		f:    os.NewFile(uintptr(fd), "inotify"),
+		wds:  make(map[string]int),
+		c:    make(chan struct{}, 1),
+	}
+	go n.read()
+	return n, nil
+}
+
+func (n *inotify) read() {
+	buf := make([]byte, 4096)
+	for {
+		if _, err := n.f.Read(buf); err != nil {
+			return
+		}
+		select {
+		case n.c <- struct{}{}:
+		default:
+			// a rescan is already pending
+		}
+	}
+}
+
+func (n *inotify) C() <-chan struct{} {
+	return n.c
+}
+
+func (n *inotify) watch(dirs map[string]bool) (added bool, err error) {
+	for dir := range dirs {
+		if _, ok := n.wds[dir]; ok {
+			continue
+		}
+		wd, e := syscall.InotifyAddWatch(n.fd, filepath.Join(n.root, filepath.FromSlash(dir)), inotifyMask)
+		if e != nil {
+			if e != syscall.ENOENT && e != syscall.ENOTDIR && err == nil {
+				err = os.NewSyscallError("inotify_add_watch", e)
+			}
+			continue
+		}
+		n.wds[dir] = wd
+		added = true
+	}
+
+	for dir, wd := range n.wds {
+		if !dirs[dir] {
+			// fails if the directory was removed, which removes the watch anyway
+			syscall.InotifyRmWatch(n.fd, uint32(wd))
+			delete(n.wds, dir)
+		}
+	}
+	return
+}
+
+func (n *inotify) close() error {
+	return n.f.Close()
+}
@@ -0,0 +1,10 @@
+//go:build !linux
+// +build !linux
+
+package doublestar
+
+import "errors"
+
+func newNotifier(root string) (notifier, error) {
+	return nil, errors.New("inotify is only supported on Linux")
+}
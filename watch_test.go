@@ -0,0 +1,145 @@
+package doublestar
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+)
+
+func TestWatch(t *testing.T) {
+	testWatchWith(t, func(string) []WatchOption {
+		return []WatchOption{WithPollInterval(10 * time.Millisecond)}
+	})
+}
+
+func TestWatchWithInotify(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("inotify is only supported on Linux")
+	}
+
+	// the poll interval is long enough that only inotify can find the changes
+	testWatchWith(t, func(tmp string) []WatchOption {
+		return []WatchOption{WithPollInterval(time.Hour), WithInotify(tmp)}
+	})
+}
+
+func testWatchWith(t *testing.T, opts func(tmp string) []WatchOption) {
+	tmp := t.TempDir()
+	mkdirp(tmp, "a")
+	touch(tmp, "a", "x.txt")
+	touch(tmp, "a", "y.go")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	events := Watch(ctx, os.DirFS(tmp), []string{"**/*.txt", "a/x.*"}, opts(tmp)...)
+
+	steps := []struct {
+		change   func()
+		expected []WatchEvent
+	}{
+		{func() {
+			touch(tmp, "a", "y.txt")
+			touch(tmp, "a", "z.go")
+		}, []WatchEvent{{Op: WatchCreate, Path: "a/y.txt"}}},
+		{func() {
+			writeFile(t, filepath.Join(tmp, "a", "x.txt"), "changed")
+		}, []WatchEvent{{Op: WatchModify, Path: "a/x.txt"}}},
+		{func() {
+			os.Remove(filepath.Join(tmp, "a", "y.txt"))
+		}, []WatchEvent{{Op: WatchRemove, Path: "a/y.txt"}}},
+		{func() {
+			// new directories are watched, too
+			mkdirp(tmp, "b", "c")
+			touch(tmp, "b", "c", "d.txt")
+		}, []WatchEvent{{Op: WatchCreate, Path: "b/c/d.txt"}}},
+		{func() {
+			touch(tmp, "b", "c", "e.txt")
+		}, []WatchEvent{{Op: WatchCreate, Path: "b/c/e.txt"}}},
+	}
+
+	for idx, step := range steps {
+		step.change()
+		for _, expected := range step.expected {
+			select {
+			case e, ok := <-events:
+				if !ok {
+					t.Fatalf("#%v. Watch closed the channel early", idx)
+				}
+				if e != expected {
+					t.Errorf("#%v. Watch sent %+v want %+v", idx, e, expected)
+				}
+			case <-time.After(5 * time.Second):
+				t.Fatalf("#%v. Watch did not send %+v", idx, expected)
+			}
+		}
+	}
+
+	cancel()
+	for e := range events {
+		// the channel must be closed; anything left over is unexpected
+		t.Errorf("Watch sent unexpected %+v", e)
+	}
+}
+
+func TestWatchBadPattern(t *testing.T) {
+	events := Watch(context.Background(), os.DirFS("."), []string{"*.go", "a/["})
+	select {
+	case e := <-events:
+		if e.Err != ErrBadPattern {
+			t.Errorf("Watch(`a/[`) sent %+v want ErrBadPattern", e)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Watch(`a/[`) did not send an error")
+	}
+	if _, ok := <-events; ok {
+		t.Errorf("Watch(`a/[`) did not close the channel")
+	}
+}
+
+func TestWatchWithGlobOptions(t *testing.T) {
+	events := Watch(context.Background(), os.DirFS("."), []string{"nope/*"}, WithGlobOptions(WithFailOnPatternNotExist()))
+	select {
+	case e := <-events:
+		if e.Err == nil {
+			t.Errorf("Watch(`nope/*`, WithGlobOptions(WithFailOnPatternNotExist())) sent %+v want an error", e)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Watch(`nope/*`, WithGlobOptions(WithFailOnPatternNotExist())) did not send an error")
+	}
+	if _, ok := <-events; ok {
+		t.Errorf("Watch(`nope/*`, WithGlobOptions(WithFailOnPatternNotExist())) did not close the channel")
+	}
+}
+
+func TestWatchOpString(t *testing.T) {
+	tests := []struct {
+		op       WatchOp
+		expected string
+	}{
+		{WatchCreate, "Create"},
+		{WatchRemove, "Remove"},
+		{WatchModify, "Modify"},
+		{WatchOp(0), "WatchOp(0)"},
+	}
+
+	for idx, tt := range tests {
+		if s := tt.op.String(); s != tt.expected {
+			t.Errorf("#%v. WatchOp(%d).String() = %q want %q", idx, int(tt.op), s, tt.expected)
+		}
+	}
+}
+
+// Replaces the contents of `name` atomically, so a Watch can't see it
+// half-written
+func writeFile(t *testing.T, name, data string) {
+	tmp := filepath.Join(filepath.Dir(name), ".tmp")
+	if err := os.WriteFile(tmp, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Rename(tmp, name); err != nil {
+		t.Fatal(err)
+	}
+}
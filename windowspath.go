@@ -0,0 +1,168 @@
+package doublestar
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// WindowsPath is a dialect for Windows-style paths and patterns that can be
+// used on any OS. In this dialect, both `\` and `/` are path separators, and
+// paths may begin with a volume name: a drive letter such as `C:`, a UNC share
+// such as `\\server\share`, or a device path such as `\\?\C:`.
+//
+// Because `\` is a path separator, it cannot also be used to escape meta
+// characters in patterns. Escape sets an alternative escape character, such as
+// `^` (like cmd.exe) or "`" (like PowerShell). If Escape is zero (or `\` or
+// `/`), escaping is disabled, which is how PathMatch behaves on Windows.
+type WindowsPath struct {
+	Escape rune
+}
+
+// VolumeName returns the leading volume name of `p`, or an empty string if
+// there isn't one. Given `C:\foo\bar`, it returns `C:`; given
+// `\\server\share\foo`, it returns `\\server\share`; given `\\?\C:\foo`, it
+// returns `\\?\C:`. Note that `C:foo` has a volume name, but is relative to the
+// current directory of drive C, whereas `C:\foo` is absolute.
+func (w WindowsPath) VolumeName(p string) string {
+	if len(p) >= 2 && p[1] == ':' && isASCIILetter(p[0]) {
+		return p[:2]
+	}
+	if len(p) < 3 || !isWindowsSeparator(p[0]) || !isWindowsSeparator(p[1]) {
+		return ""
+	}
+
+	if (p[2] == '?' || p[2] == '.') && (len(p) == 3 || isWindowsSeparator(p[3])) {
+		// device path, such as `\\?\C:` or `\\.\pipe`; `\\?\UNC\server\share` is a
+		// UNC share in disguise
+		if len(p) <= 4 {
+			return p
+		}
+		end := nextWindowsSegment(p, 4)
+		if strings.EqualFold(p[4:end], "UNC") {
+			if end = uncShareEnd(p, end+1); end == -1 {
+				return ""
+			}
+		}
+		return p[:end]
+	}
+
+	if end := uncShareEnd(p, 2); end != -1 {
+		return p[:end]
+	}
+	return ""
+}
+
+// SplitPattern is like the SplitPattern function, but for this dialect. It
+// returns the base path of `p`, as a Windows path that can be passed to
+// os.DirFS(), and the rest of the pattern, converted so it can be passed to
+// Glob() or GlobWalk(): path separators are replaced with `/`, and escaped
+// characters are escaped with `\`.
+//
+// Unlike SplitPattern, volume names are kept intact: `C:\**` is split into
+// `C:\` and `**`, `C:**` into `C:.` (the current directory of drive C) and
+// `**`, and `\\server\share\**` into `\\server\share\` and `**`.
+func (w WindowsPath) SplitPattern(p string) (base, pattern string) {
+	vol := w.VolumeName(p)
+	base, pattern = SplitPattern(w.toSlash(p[len(vol):]))
+	if base == "." {
+		if vol == "" {
+			return ".", pattern
+		}
+		return vol + ".", pattern
+	}
+	return vol + strings.ReplaceAll(base, "/", `\`), pattern
+}
+
+// ValidatePattern is like the ValidatePattern function, but for this dialect.
+func (w WindowsPath) ValidatePattern(p string) bool {
+	return ValidatePattern(w.toSlash(p[len(w.VolumeName(p)):]))
+}
+
+// Match is like MatchWithOptions, but for this dialect: `pattern` and `name`
+// may use either `\` or `/` as path separators, and Escape is used to escape
+// meta characters in `pattern`. Volume names are matched literally, but case
+// insensitively, like Windows does; meta characters in a volume name are not
+// expanded. The WithSeparator option is ignored.
+func (w WindowsPath) Match(pattern, name string, opts ...MatchOption) (bool, error) {
+	patternVol := w.VolumeName(pattern)
+	nameVol := w.VolumeName(name)
+
+	matched, err := MatchWithOptions(
+		w.toSlash(pattern[len(patternVol):]),
+		strings.ReplaceAll(name[len(nameVol):], `\`, "/"),
+		append(opts[:len(opts):len(opts)], WithSeparator('/'))...,
+	)
+	if err != nil || !matched {
+		return false, err
+	}
+	return strings.EqualFold(strings.ReplaceAll(patternVol, `\`, "/"), strings.ReplaceAll(nameVol, `\`, "/")), nil
+}
+
+// Returns the escape character, or zero if escaping is disabled.
+func (w WindowsPath) escape() rune {
+	if w.Escape == '\\' || w.Escape == '/' {
+		return 0
+	}
+	return w.Escape
+}
+
+// Converts a pattern in this dialect to one that can be passed to Match() or
+// Glob(): separators are replaced with `/` and characters escaped with Escape
+// are escaped with `\` instead. A dangling escape character is converted to a
+// dangling `\`, so the result is still an invalid pattern.
+func (w WindowsPath) toSlash(p string) string {
+	escape := w.escape()
+	var b strings.Builder
+	b.Grow(len(p))
+	for i := 0; i < len(p); {
+		r, n := utf8.DecodeRuneInString(p[i:])
+		i += n
+		switch {
+		case escape != 0 && r == escape:
+			b.WriteByte('\\')
+			if i < len(p) {
+				r, n = utf8.DecodeRuneInString(p[i:])
+				i += n
+				b.WriteRune(r)
+			}
+		case r == '\\':
+			b.WriteByte('/')
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// If `p[start:]` begins with `server\share`, returns the index of the end of
+// the share name. Otherwise, returns -1.
+func uncShareEnd(p string, start int) int {
+	serverEnd := nextWindowsSegment(p, start)
+	if serverEnd == start || serverEnd == len(p) {
+		return -1
+	}
+	shareEnd := nextWindowsSegment(p, serverEnd+1)
+	if shareEnd == serverEnd+1 {
+		return -1
+	}
+	return shareEnd
+}
+
+// Returns the index of the next separator in `p`, starting at `start`, or
+// len(p) if there isn't one.
+func nextWindowsSegment(p string, start int) int {
+	for i := start; i < len(p); i++ {
+		if isWindowsSeparator(p[i]) {
+			return i
+		}
+	}
+	return len(p)
+}
+
+func isWindowsSeparator(c byte) bool {
+	return c == '\\' || c == '/'
+}
+
+func isASCIILetter(c byte) bool {
+	return ('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z')
+}
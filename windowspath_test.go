@@ -0,0 +1,112 @@
+package doublestar
+
+import "testing"
+
+func TestWindowsPathVolumeName(t *testing.T) {
+	tests := []struct {
+		path, expected string
+	}{
+		{`C:\foo\bar`, `C:`},
+		{`c:foo`, `c:`},
+		{`C:`, `C:`},
+		{`C:/foo`, `C:`},
+		{`1:\foo`, ``},
+		{`\foo\bar`, ``},
+		{`foo\bar`, ``},
+		{`\\server\share\foo`, `\\server\share`},
+		{`//server/share/foo`, `//server/share`},
+		{`\\server\share`, `\\server\share`},
+		{`\\server`, ``},
+		{`\\server\`, ``},
+		{`\\\share`, ``},
+		{`\\?\C:\foo`, `\\?\C:`},
+		{`\\.\pipe\name`, `\\.\pipe`},
+		{`\\?\UNC\server\share\foo`, `\\?\UNC\server\share`},
+		{`\\?\UNC\server`, ``},
+		{`\\?`, `\\?`},
+		{`\\?\`, `\\?\`},
+	}
+
+	for idx, tt := range tests {
+		if vol := (WindowsPath{}).VolumeName(tt.path); vol != tt.expected {
+			t.Errorf("#%v. VolumeName(%#q) = %#q want %#q", idx, tt.path, vol, tt.expected)
+		}
+	}
+}
+
+func TestWindowsPathSplitPattern(t *testing.T) {
+	tests := []struct {
+		escape                  rune
+		pattern, base, expected string
+	}{
+		{0, `C:\x\**`, `C:\x`, `**`},
+		{0, `C:/x/**`, `C:\x`, `**`},
+		{0, `C:x\**`, `C:x`, `**`},
+		{0, `C:\**`, `C:\`, `**`},
+		{0, `C:**`, `C:.`, `**`},
+		{0, `\\server\share\**`, `\\server\share\`, `**`},
+		{0, `\\server\share\dir\*.txt`, `\\server\share\dir`, `*.txt`},
+		{0, `\\?\C:\dir\*`, `\\?\C:\dir`, `*`},
+		{0, `\dir\*\file`, `\dir`, `*/file`},
+		{0, `dir\sub\[ab]*`, `dir\sub`, `[ab]*`},
+		{0, `*.txt`, `.`, `*.txt`},
+		{'^', `C:\a^*b\*.txt`, `C:\a*b`, `*.txt`},
+		{'^', `C:\a\b^[1^]*`, `C:\a`, `b\[1\]*`},
+		{'`', "C:\\a`{b`}\\*", `C:\a{b}`, `*`},
+	}
+
+	for idx, tt := range tests {
+		base, pattern := WindowsPath{Escape: tt.escape}.SplitPattern(tt.pattern)
+		if base != tt.base || pattern != tt.expected {
+			t.Errorf("#%v. WindowsPath{%q}.SplitPattern(%#q) = %#q, %#q want %#q, %#q", idx, tt.escape, tt.pattern, base, pattern, tt.base, tt.expected)
+		}
+	}
+}
+
+func TestWindowsPathMatch(t *testing.T) {
+	tests := []struct {
+		escape        rune
+		pattern, name string
+		expected      bool
+		err           error
+	}{
+		{0, `C:\foo\*.txt`, `C:\foo\a.txt`, true, nil},
+		{0, `C:\foo\*.txt`, `c:/foo/a.txt`, true, nil},
+		{0, `C:\foo\*.txt`, `D:\foo\a.txt`, false, nil},
+		{0, `C:\foo\*.txt`, `C:foo\a.txt`, false, nil},
+		{0, `C:foo\*.txt`, `C:foo\a.txt`, true, nil},
+		{0, `\foo\*.txt`, `C:\foo\a.txt`, false, nil},
+		{0, `C:\**\*.txt`, `C:\a\b\c.txt`, true, nil},
+		{0, `C:\*`, `C:\a\b`, false, nil},
+		{0, `\\server\share\**`, `\\SERVER\share\a\b`, true, nil},
+		{0, `\\server\share\**`, `\\other\share\a`, false, nil},
+		{0, `\\?\C:\*`, `\\?\c:\a`, true, nil},
+		{0, `a\[b]`, `a\b`, true, nil},
+		{0, `a\{b,c}\d`, `a/c/d`, true, nil},
+		{0, `a^*`, `a^b`, true, nil},
+		{'^', `a^*`, `a*`, true, nil},
+		{'^', `a^*`, `ab`, false, nil},
+		{'^', `a\^[b^]`, `a\[b]`, true, nil},
+		{'^', `a\^^`, `a\^`, true, nil},
+		{'^', `a\^`, `a\^`, false, ErrBadPattern},
+		{'`', "a\\`*", `a\*`, true, nil},
+		{0, `a\[`, `a\[`, false, ErrBadPattern},
+	}
+
+	for idx, tt := range tests {
+		w := WindowsPath{Escape: tt.escape}
+		matched, err := w.Match(tt.pattern, tt.name)
+		if matched != tt.expected || err != tt.err {
+			t.Errorf("#%v. WindowsPath{%q}.Match(%#q, %#q) = %v, %v want %v, %v", idx, tt.escape, tt.pattern, tt.name, matched, err, tt.expected, tt.err)
+		}
+
+		if valid := w.ValidatePattern(tt.pattern); valid != (tt.err == nil) {
+			t.Errorf("#%v. WindowsPath{%q}.ValidatePattern(%#q) = %v want %v", idx, tt.escape, tt.pattern, valid, tt.err == nil)
+		}
+	}
+
+	matched, err := WindowsPath{}.Match(`C:\FOO\*.TXT`, `c:\foo\a.txt`, WithCaseInsensitive())
+	if !matched || err != nil {
+		t.Errorf("WindowsPath{}.Match(`C:\\FOO\\*.TXT`, `c:\\foo\\a.txt`, WithCaseInsensitive()) = %v, %v want true, nil", matched, err)
+	}
+}